@@ -2,7 +2,10 @@ module github.com/sbiemont/gocsv
 
 go 1.21.0
 
-require github.com/smartystreets/goconvey v1.8.1
+require (
+	github.com/smartystreets/goconvey v1.8.1
+	golang.org/x/text v0.21.0
+)
 
 require (
 	github.com/gopherjs/gopherjs v1.17.2 // indirect
@@ -1,24 +1,76 @@
 package gocsv
 
 import (
+	"context"
+	"encoding/csv"
+	"errors"
 	"fmt"
+	"io"
 
 	"github.com/sbiemont/gocsv/internal"
 )
 
-// Decode a csv struct into the given type of data
-func Decode[T any](data [][]string) ([]T, error) {
-	// Prepare data
-	ct, err := internal.NewCacheTags[T]()
+// Decoder decodes csv records into a given type of data
+// A decoder is not safe for concurrent use
+type Decoder[T any] struct {
+	ct    internal.CacheTags[T]
+	merge internal.CacheTags[T] // empty values leave the fields untouched (see DecodeInto)
+	cm    internal.CacheUnmarshaler
+}
+
+// NewDecoder init a decoder using the given options
+func NewDecoder[T any](opts ...Option) (*Decoder[T], error) {
+	ct, err := internal.NewCacheTags[T](opts...)
 	if err != nil {
 		return nil, err
 	}
 	cm := internal.NewCacheUnmarshaler()
+	err = internal.PrepareUnmarshaler(ct, cm)
+	if err != nil {
+		return nil, err
+	}
+	return &Decoder[T]{
+		ct:    ct,
+		merge: ct.Merging(),
+		cm:    cm,
+	}, nil
+}
+
+// Decode a csv struct into the given type of data
+func Decode[T any](data [][]string, opts ...Option) ([]T, error) {
+	dec, err := NewDecoder[T](opts...)
+	if err != nil {
+		return nil, err
+	}
+	return dec.Decode(data)
+}
+
+// DecodeContext decodes a csv struct into the given type of data, until the context is done
+func DecodeContext[T any](ctx context.Context, data [][]string, opts ...Option) ([]T, error) {
+	dec, err := NewDecoder[T](opts...)
+	if err != nil {
+		return nil, err
+	}
+	return dec.DecodeContext(ctx, data)
+}
+
+// Decode a csv struct into the decoder type of data
+func (dec *Decoder[T]) Decode(data [][]string) ([]T, error) {
+	return dec.DecodeContext(context.Background(), data)
+}
+
+// DecodeContext decodes a csv struct into the decoder type of data, until the context is done
+// The context is checked before each row, and given to the lib.ContextUnmarshaler fields
+func (dec *Decoder[T]) DecodeContext(ctx context.Context, data [][]string) ([]T, error) {
 	res := make([]T, len(data))
 
 	// Read all
 	for i, row := range data {
-		err := internal.Unmarshal(ct, cm, row, &res[i])
+		err := checkContext(ctx, i)
+		if err != nil {
+			return nil, err
+		}
+		err = internal.UnmarshalContext(ctx, dec.ct, dec.cm, internal.Row{Index: i}, row, &res[i])
 		if err != nil {
 			return nil, fmt.Errorf("row %d: %w", i, err)
 		}
@@ -26,19 +78,88 @@ func Decode[T any](data [][]string) ([]T, error) {
 	return res, nil
 }
 
-// Encode into a csv struct
-func Encode[T any](data []T) ([][]string, error) {
-	// Prepare data
-	ct, err := internal.NewCacheTags[T]()
+// DecodeReader reads and decodes every csv record of the reader into the decoder type of data
+// The source names the reader (ie. a file name), and fills the `csv:"@source"` fields
+func (dec *Decoder[T]) DecodeReader(r *csv.Reader, source string) ([]T, error) {
+	return dec.DecodeReaderContext(context.Background(), r, source)
+}
+
+// DecodeReaderContext reads and decodes every csv record of the reader, until the context is done
+func (dec *Decoder[T]) DecodeReaderContext(ctx context.Context, r *csv.Reader, source string) ([]T, error) {
+	var res []T
+	err := readRecords(ctx, r, source, func(row internal.Row, record []string) error {
+		var item T
+		err := internal.UnmarshalContext(ctx, dec.ct, dec.cm, row, record, &item)
+		if err != nil {
+			return err
+		}
+		res = append(res, item)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Encoder encodes a given type of data into csv records
+// An encoder is not safe for concurrent use
+type Encoder[T any] struct {
+	ct internal.CacheTags[T]
+	cm internal.CacheMarshaler
+}
+
+// NewEncoder init an encoder using the given options
+func NewEncoder[T any](opts ...Option) (*Encoder[T], error) {
+	ct, err := internal.NewCacheTags[T](opts...)
 	if err != nil {
 		return nil, err
 	}
 	cm := internal.NewCacheMarshaler()
+	err = internal.PrepareMarshaler(ct, cm)
+	if err != nil {
+		return nil, err
+	}
+	return &Encoder[T]{
+		ct: ct,
+		cm: cm,
+	}, nil
+}
+
+// Encode into a csv struct
+func Encode[T any](data []T, opts ...Option) ([][]string, error) {
+	enc, err := NewEncoder[T](opts...)
+	if err != nil {
+		return nil, err
+	}
+	return enc.Encode(data)
+}
+
+// EncodeContext encodes into a csv struct, until the context is done
+func EncodeContext[T any](ctx context.Context, data []T, opts ...Option) ([][]string, error) {
+	enc, err := NewEncoder[T](opts...)
+	if err != nil {
+		return nil, err
+	}
+	return enc.EncodeContext(ctx, data)
+}
+
+// Encode into a csv struct
+func (enc *Encoder[T]) Encode(data []T) ([][]string, error) {
+	return enc.EncodeContext(context.Background(), data)
+}
+
+// EncodeContext encodes into a csv struct, until the context is done (checked before each row)
+func (enc *Encoder[T]) EncodeContext(ctx context.Context, data []T) ([][]string, error) {
 	res := make([][]string, len(data))
 
 	// Read all
 	for i, item := range data {
-		row, err := internal.Marshal(ct, cm, item)
+		err := checkContext(ctx, i)
+		if err != nil {
+			return nil, err
+		}
+		row, err := internal.MarshalContext(ctx, enc.ct, enc.cm, i, item)
 		if err != nil {
 			return nil, fmt.Errorf("row %d: %w", i, err)
 		}
@@ -46,3 +167,74 @@ func Encode[T any](data []T) ([][]string, error) {
 	}
 	return res, nil
 }
+
+// EncodeWriter encodes the rows and writes them into the csv writer (flushed at the end)
+func (enc *Encoder[T]) EncodeWriter(w *csv.Writer, data []T) error {
+	return enc.EncodeWriterContext(context.Background(), w, data)
+}
+
+// EncodeWriterContext encodes the rows and writes them into the csv writer, until the context is done
+// The context is checked before each row (the rows already written are flushed)
+func (enc *Encoder[T]) EncodeWriterContext(ctx context.Context, w *csv.Writer, data []T) error {
+	return writeRecords(ctx, w, len(data), func(i int) ([]string, error) {
+		return internal.MarshalContext(ctx, enc.ct, enc.cm, i, data[i])
+	})
+}
+
+// writeRecords encodes and writes n records into the writer, then flushes it
+func writeRecords(ctx context.Context, w *csv.Writer, n int, fn func(int) ([]string, error)) error {
+	err := func() error {
+		for i := 0; i < n; i++ {
+			err := checkContext(ctx, i)
+			if err != nil {
+				return err
+			}
+			record, err := fn(i)
+			if err != nil {
+				return fmt.Errorf("row %d: %w", i, err)
+			}
+			err = w.Write(record)
+			if err != nil {
+				return fmt.Errorf("row %d: %w", i, err)
+			}
+		}
+		return nil
+	}()
+	w.Flush()
+	if err != nil {
+		return err
+	}
+	return w.Error()
+}
+
+// readRecords reads every csv record of the reader, giving its index, physical line and source
+func readRecords(ctx context.Context, r *csv.Reader, source string, fn func(internal.Row, []string) error) error {
+	for i := 0; ; i++ {
+		err := checkContext(ctx, i)
+		if err != nil {
+			return err
+		}
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		line, _ := r.FieldPos(0)
+		err = fn(internal.Row{Index: i, Line: line, Source: source}, record)
+		if err != nil {
+			return fmt.Errorf("row %d: %w", i, err)
+		}
+	}
+}
+
+// checkContext returns the context error (if done), wrapped with the number of processed rows
+func checkContext(ctx context.Context, processed int) error {
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("interrupted after %d rows: %w", processed, ctx.Err())
+	default:
+		return nil
+	}
+}
@@ -1,13 +1,17 @@
 package gocsv
 
 import (
+	"context"
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"os"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/sbiemont/gocsv/lib"
 	. "github.com/smartystreets/goconvey/convey"
 )
 
@@ -74,3 +78,239 @@ func TestCSV(t *testing.T) {
 		So(data2, ShouldNotBeNil)
 	})
 }
+
+func TestCSVParts(t *testing.T) {
+	type testStruct struct {
+		ID   int     `csv:"0"`
+		Name string  `csv:"1"`
+		Lat  float64 `csv:"7,part=0,sep=\\,,trim"`
+		Lon  float64 `csv:"7,part=1,sep=\\,,trim"`
+	}
+
+	Convey("read all", t, func() {
+		f, err := os.Open(filename)
+		So(err, ShouldBeNil)
+		defer f.Close()
+
+		csvReader := csv.NewReader(f)
+		csvReader.Comma = ';'
+		data, err := csvReader.ReadAll()
+		So(err, ShouldBeNil)
+
+		res, err := Decode[testStruct](data[1:])
+		So(err, ShouldBeNil)
+		So(res[0], ShouldResemble, testStruct{ID: 16, Name: "Erdre", Lat: 47.20631899905218, Lon: -1.5161410001042461})
+	})
+}
+
+func TestCSVReader(t *testing.T) {
+	type testStruct struct {
+		ID     int    `csv:"0"`
+		Name   string `csv:"1"`
+		Line   int    `csv:"@line"`
+		Row    int    `csv:"@row"`
+		Source string `csv:"@source"`
+	}
+
+	Convey("read all", t, func() {
+		f, err := os.Open(filename)
+		So(err, ShouldBeNil)
+		defer f.Close()
+
+		csvReader := csv.NewReader(f)
+		csvReader.Comma = ';'
+		_, err = csvReader.Read() // skip headers (first row)
+		So(err, ShouldBeNil)
+
+		dec, err := NewDecoder[testStruct]()
+		So(err, ShouldBeNil)
+		res, err := dec.DecodeReader(csvReader, filename)
+		So(err, ShouldBeNil)
+		So(res[0], ShouldResemble, testStruct{ID: 16, Name: "Erdre", Line: 2, Row: 0, Source: filename})
+		So(res[1].Line, ShouldEqual, 3)
+	})
+
+	Convey("multi-line record", t, func() {
+		csvReader := csv.NewReader(strings.NewReader("1,\"a\nb\"\n2,c\n"))
+		dec, err := NewDecoder[testStruct]()
+		So(err, ShouldBeNil)
+		res, err := dec.DecodeReader(csvReader, "")
+		So(err, ShouldBeNil)
+		So(res, ShouldResemble, []testStruct{{ID: 1, Name: "a\nb", Line: 1}, {ID: 2, Name: "c", Line: 3, Row: 1}})
+	})
+
+	Convey("invalid record", t, func() {
+		csvReader := csv.NewReader(strings.NewReader("1,a\nx,b\n"))
+		dec, err := NewDecoder[testStruct]()
+		So(err, ShouldBeNil)
+		_, err = dec.DecodeReader(csvReader, "")
+		So(err, ShouldBeError, `row 1: col 0: strconv.ParseInt: parsing "x": invalid syntax`)
+	})
+}
+
+func TestDecoderEncoder(t *testing.T) {
+	type testStruct struct {
+		ID   int       `csv:"0"`
+		Date time.Time `csv:"1"`
+	}
+
+	opt := WithConverter(
+		func(tm time.Time) (string, error) { return tm.Format(time.DateOnly), nil },
+		func(s string) (time.Time, error) { return time.Parse(time.DateOnly, s) },
+	)
+	rows := []testStruct{
+		{ID: 1, Date: time.Date(2023, 12, 2, 0, 0, 0, 0, time.UTC)},
+		{ID: 2, Date: time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)},
+	}
+	records := [][]string{
+		{"1", "2023-12-02"},
+		{"2", "2024-01-03"},
+	}
+
+	Convey("decoder", t, func() {
+		dec, err := NewDecoder[testStruct](opt)
+		So(err, ShouldBeNil)
+		res, err := dec.Decode(records)
+		So(err, ShouldBeNil)
+		So(res, ShouldResemble, rows)
+	})
+
+	Convey("encoder", t, func() {
+		enc, err := NewEncoder[testStruct](opt)
+		So(err, ShouldBeNil)
+		res, err := enc.Encode(rows)
+		So(err, ShouldBeNil)
+		So(res, ShouldResemble, records)
+	})
+
+	Convey("pointer rows", t, func() {
+		res, err := Decode[*testStruct](records, opt)
+		So(err, ShouldBeNil)
+		So(res, ShouldResemble, []*testStruct{&rows[0], &rows[1]})
+
+		out, err := Encode(res, opt)
+		So(err, ShouldBeNil)
+		So(out, ShouldResemble, records)
+
+		_, err = Encode([]*testStruct{&rows[0], nil}, opt)
+		So(err, ShouldBeError, "row 1: nil value found")
+	})
+}
+
+func TestMerge(t *testing.T) {
+	type testStruct struct {
+		ID    string `csv:"0"`
+		Name  string `csv:"1"`
+		Count int    `csv:"2"`
+	}
+
+	Convey("decode into", t, func() {
+		items := []*testStruct{{ID: "a", Name: "A", Count: 1}, nil}
+		changed, err := DecodeInto([][]string{{"", "", "2"}, {"b", "B", ""}}, items)
+		So(err, ShouldBeNil)
+		So(changed, ShouldResemble, [][]string{{"Count"}, {"ID", "Name"}})
+		So(items, ShouldResemble, []*testStruct{{ID: "a", Name: "A", Count: 2}, {ID: "b", Name: "B"}})
+
+		_, err = DecodeInto([][]string{{"a"}}, items)
+		So(err, ShouldBeError, "1 rows expected, got 2 items")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		dec, err := NewDecoder[testStruct]()
+		So(err, ShouldBeNil)
+		_, err = dec.DecodeIntoContext(ctx, [][]string{{"a"}}, items[:1])
+		So(err, ShouldBeError, "interrupted after 0 rows: context canceled")
+	})
+
+	Convey("merge by key", t, func() {
+		existing := map[string]*testStruct{
+			"a": {ID: "a", Name: "A", Count: 1},
+			"b": {ID: "b", Name: "B", Count: 2},
+		}
+		changed, err := MergeByKey(existing, [][]string{{"b", "", "3"}, {"a", "A", ""}}, "ID")
+		So(err, ShouldBeNil)
+		So(changed, ShouldResemble, [][]string{{"Count"}, nil})
+		So(existing["a"], ShouldResemble, &testStruct{ID: "a", Name: "A", Count: 1})
+		So(existing["b"], ShouldResemble, &testStruct{ID: "b", Name: "B", Count: 3})
+
+		_, err = MergeByKey(existing, [][]string{{"a", "Z"}, {"c", "C"}}, "ID")
+		So(err, ShouldBeError, "row 1: unknown key c")
+		So(existing["a"].Name, ShouldEqual, "A") // nothing merged
+		_, err = MergeByKey(existing, [][]string{{"a"}}, "Count")
+		So(err, ShouldBeError, "key field Count is int, not string")
+	})
+}
+
+// cancelOn cancels the decoding context when reading the given value
+type cancelOn string
+
+type cancelKey struct{}
+
+func (it *cancelOn) UnmarshalCSVContext(ctx context.Context, s string) error {
+	if s == "stop" {
+		ctx.Value(cancelKey{}).(context.CancelFunc)()
+	}
+	*it = cancelOn(s)
+	return nil
+}
+
+func (it cancelOn) MarshalCSVField(fc lib.FieldContext) (string, error) {
+	if it == "stop" {
+		fc.Context.Value(cancelKey{}).(context.CancelFunc)()
+	}
+	return string(it), nil
+}
+
+func TestContext(t *testing.T) {
+	type testStruct struct {
+		Value cancelOn `csv:"0"`
+	}
+
+	records := [][]string{{"a"}, {"stop"}, {"b"}}
+
+	Convey("decode until canceled", t, func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ctx = context.WithValue(ctx, cancelKey{}, cancel)
+
+		_, err := DecodeContext[testStruct](ctx, records)
+		So(err, ShouldBeError, "interrupted after 2 rows: context canceled")
+		So(errors.Is(err, context.Canceled), ShouldBeTrue)
+	})
+
+	Convey("encode when already canceled", t, func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := EncodeContext(ctx, []testStruct{{Value: "a"}})
+		So(err, ShouldBeError, "interrupted after 0 rows: context canceled")
+	})
+
+	Convey("decode a stream until canceled", t, func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ctx = context.WithValue(ctx, cancelKey{}, cancel)
+
+		dec, err := NewDecoder[testStruct]()
+		So(err, ShouldBeNil)
+		_, err = dec.DecodeReaderContext(ctx, csv.NewReader(strings.NewReader("a\nstop\nb\n")), "")
+		So(err, ShouldBeError, "interrupted after 2 rows: context canceled")
+	})
+
+	Convey("encode a stream until canceled", t, func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ctx = context.WithValue(ctx, cancelKey{}, cancel)
+
+		var buf strings.Builder
+		enc, err := NewEncoder[testStruct]()
+		So(err, ShouldBeNil)
+		err = enc.EncodeWriterContext(ctx, csv.NewWriter(&buf), []testStruct{{Value: "a"}, {Value: "stop"}, {Value: "b"}})
+		So(err, ShouldBeError, "interrupted after 2 rows: context canceled")
+		So(buf.String(), ShouldEqual, "a\nstop\n")
+
+		buf.Reset()
+		So(enc.EncodeWriter(csv.NewWriter(&buf), []testStruct{{Value: "a"}, {Value: "b"}}), ShouldBeNil)
+		So(buf.String(), ShouldEqual, "a\nb\n")
+	})
+}
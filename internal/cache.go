@@ -0,0 +1,59 @@
+package internal
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/sbiemont/gocsv/lib"
+)
+
+// cacheKey identifies a cached function by field index and by type
+// (several fields may read the same column, an interface field may use a different type on each row)
+type cacheKey struct {
+	field int
+	typ   reflect.Type
+}
+
+// Interfaces checked by the marshalers and unmarshalers
+var (
+	csvMarshalerType       = reflect.TypeOf((*lib.Marshaler)(nil)).Elem()
+	csvUnmarshalerType     = reflect.TypeOf((*lib.Unmarshaler)(nil)).Elem()
+	contextUnmarshalerType = reflect.TypeOf((*lib.ContextUnmarshaler)(nil)).Elem()
+	textMarshalerType      = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType    = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	binaryMarshalerType    = reflect.TypeOf((*encoding.BinaryMarshaler)(nil)).Elem()
+	binaryUnmarshalerType  = reflect.TypeOf((*encoding.BinaryUnmarshaler)(nil)).Elem()
+	jsonMarshalerType      = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	jsonUnmarshalerType    = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	multiMarshalerType     = reflect.TypeOf((*lib.MultiMarshaler)(nil)).Elem()
+	multiUnmarshalerType   = reflect.TypeOf((*lib.MultiUnmarshaler)(nil)).Elem()
+	stringerType           = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+	zeroerType             = reflect.TypeOf((*lib.Zeroer)(nil)).Elem()
+)
+
+// receiver returns a function converting a value of the given type into the given interface,
+// using the value or the pointer method set (returns nil if not implemented)
+func receiver(typ reflect.Type, iface reflect.Type) func(reflect.Value) any {
+	switch {
+	case typ.Implements(iface):
+		return reflect.Value.Interface
+	case reflect.PointerTo(typ).Implements(iface):
+		return func(v reflect.Value) any {
+			return addr(v).Interface()
+		}
+	default:
+		return nil
+	}
+}
+
+// addr returns the address of the value (or of a copy, if not addressable)
+func addr(v reflect.Value) reflect.Value {
+	if v.CanAddr() {
+		return v.Addr()
+	}
+	ptr := reflect.New(v.Type())
+	ptr.Elem().Set(v)
+	return ptr
+}
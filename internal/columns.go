@@ -0,0 +1,139 @@
+package internal
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/sbiemont/gocsv/lib"
+)
+
+// parseColumns reads the columns of a field (ie. "3", "3+4" or "cols=date|time" using the header)
+func parseColumns(cfg Config, s string) ([]int, error) {
+	var cols []int
+	if names, ok := strings.CutPrefix(s, "cols="); ok {
+		for _, name := range strings.Split(names, "|") {
+			col := slices.Index(cfg.header, name)
+			if col < 0 {
+				return nil, fmt.Errorf("unknown header %q", name)
+			}
+			cols = append(cols, col)
+		}
+	} else {
+		for _, c := range strings.Split(s, "+") {
+			col, err := strconv.Atoi(c)
+			if err != nil {
+				return nil, fmt.Errorf("invalid column %q", s)
+			}
+			if col < 0 {
+				return nil, fmt.Errorf("negative column %d", col)
+			}
+			cols = append(cols, col)
+		}
+	}
+	for k, col := range cols {
+		if slices.Contains(cols[:k], col) {
+			return nil, fmt.Errorf("duplicate column %d", col)
+		}
+	}
+	return cols, nil
+}
+
+// checkColumns checks that a multi-column field implements lib.MultiUnmarshaler or lib.MultiMarshaler
+func (t tag) checkColumns(typ reflect.Type) error {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if t.part != "" {
+		return fmt.Errorf("a part cannot use several columns")
+	}
+	if t.defaultVal != "" {
+		return fmt.Errorf("no default value allowed for several columns")
+	}
+	if receiver(typ, multiUnmarshalerType) == nil && receiver(typ, multiMarshalerType) == nil {
+		return fmt.Errorf("type %s implements neither lib.MultiUnmarshaler nor lib.MultiMarshaler", typ)
+	}
+	return nil
+}
+
+// columnInputs reads the inputs of a multi-column field (out of bounds columns are considered as empty, if allowed)
+// The inputs are normalized, and the null values are given as empty strings
+func (t tag) columnInputs(inputs []string) ([]string, error) {
+	res := make([]string, len(t.cols))
+	for k, col := range t.cols {
+		switch {
+		case col < len(inputs):
+			in, isNull := t.normalizeInput(inputs[col])
+			if !isNull {
+				res[k] = in
+			}
+		case t.empty == emptyUnset || t.empty == EmptyError:
+			return nil, fmt.Errorf("column %d out of bounds", col)
+		}
+	}
+	return res, nil
+}
+
+// isNullColumns reports whether every input is considered as empty
+func (t tag) isNullColumns(inputs []string) bool {
+	for _, in := range inputs {
+		if !t.isNull(in) {
+			return false
+		}
+	}
+	return true
+}
+
+// unmarshalColumns unmarshals the inputs of a multi-column field (using lib.MultiUnmarshaler)
+func unmarshalColumns(inputs []string, field reflect.Value) error {
+	if field.Kind() == reflect.Ptr {
+		field.Set(reflect.New(field.Type().Elem()))
+		field = field.Elem()
+	}
+	return field.Addr().Interface().(lib.MultiUnmarshaler).UnmarshalCSVColumns(inputs)
+}
+
+// marshalColumns marshals a multi-column field (using lib.MultiMarshaler), handling nil and empty values
+// The values are normalized (except the null values)
+func (t tag) marshalColumns(field reflect.Value) ([]string, error) {
+	nulls := make([]string, len(t.cols))
+	for k := range nulls {
+		nulls[k] = t.null()
+	}
+	if field.Kind() == reflect.Ptr {
+		switch {
+		case field.IsNil() && t.omitEmpty:
+			return nulls, nil
+		case field.IsNil():
+			return nil, fmt.Errorf("nil value found")
+		}
+		field = field.Elem()
+	} else if t.omitEmpty && isZero(field) {
+		return nulls, nil
+	}
+
+	res, err := receiver(field.Type(), multiMarshalerType)(field).(lib.MultiMarshaler).MarshalCSVColumns()
+	if err != nil {
+		return nil, err
+	}
+	if len(res) != len(t.cols) {
+		return nil, fmt.Errorf("%d columns expected, got %d", len(t.cols), len(res))
+	}
+	for k := range res {
+		res[k] = t.normalize(res[k], normalizeEncode)
+	}
+	return res, nil
+}
+
+// prepareColumns checks that a multi-column field can be unmarshaled or marshaled
+func prepareColumns(typ reflect.Type, iface reflect.Type) error {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if receiver(typ, iface) == nil {
+		return fmt.Errorf("type %s does not implement %s", typ, iface)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// timestamp is split into a date column and a time column
+type timestamp time.Time
+
+func (it timestamp) MarshalCSVColumns() ([]string, error) {
+	tm := time.Time(it)
+	return []string{tm.Format(time.DateOnly), tm.Format(time.TimeOnly)}, nil
+}
+
+func (it *timestamp) UnmarshalCSVColumns(ins []string) error {
+	tm, err := time.Parse(time.DateTime, ins[0]+" "+ins[1])
+	*it = timestamp(tm)
+	return err
+}
+
+// fullName is only decoded
+type fullName string
+
+func (it *fullName) UnmarshalCSVColumns(ins []string) error {
+	*it = fullName(strings.Join(ins, " "))
+	return nil
+}
+
+func TestColumns(t *testing.T) {
+	Convey("multi columns", t, func() {
+		type testStruct struct {
+			ID   int        `csv:"0"`
+			At   timestamp  `csv:"1+2"`
+			Opt  *timestamp `csv:"3+4,omitempty"`
+			Name fullName   `csv:"6+5"`
+		}
+
+		ct, err := NewCacheTags[testStruct]()
+		So(err, ShouldBeNil)
+		So(PrepareUnmarshaler(ct, NewCacheUnmarshaler()), ShouldBeNil)
+		So(PrepareMarshaler(ct, NewCacheMarshaler()), ShouldBeError, "field Name: type internal.fullName does not implement lib.MultiMarshaler")
+
+		ts := testUnmarshal(ct, NewCacheUnmarshaler(), []string{"1", "2023-02-03", "10:11:12", "", "", "Doe", "John"})
+		So(ts, ShouldResemble, testStruct{
+			ID:   1,
+			At:   timestamp(time.Date(2023, 2, 3, 10, 11, 12, 0, time.UTC)),
+			Opt:  nil,
+			Name: "John Doe",
+		})
+
+		var item testStruct
+		err = Unmarshal(ct, NewCacheUnmarshaler(), []string{"1", "2023-02-03"}, &item)
+		So(err, ShouldBeError, "field At: column 2 out of bounds")
+	})
+
+	Convey("using the header", t, func() {
+		type testStruct struct {
+			At   *timestamp `csv:"cols=date|time,omitempty,null=NA"`
+			Name fullName   `csv:"cols=first|last,null=NA"`
+		}
+
+		ct, err := NewCacheTags[testStruct](WithHeader("last", "date", "time", "first"))
+		So(err, ShouldBeNil)
+		ts := testUnmarshal(ct, NewCacheUnmarshaler(), []string{"Doe", "2023-02-03", "10:11:12", "NA"})
+		So(*ts.At, ShouldEqual, timestamp(time.Date(2023, 2, 3, 10, 11, 12, 0, time.UTC)))
+		So(ts.Name, ShouldEqual, fullName(" Doe")) // the null value is given as an empty string
+
+		ts = testUnmarshal(ct, NewCacheUnmarshaler(), []string{"Doe", "NA", "NA", "John"})
+		So(ts.At, ShouldBeNil)
+	})
+
+	Convey("encode null values", t, func() {
+		type testStruct struct {
+			At *timestamp `csv:"0+1,omitempty,null=na,upper,normalize=both"`
+		}
+
+		ct, err := NewCacheTags[testStruct]()
+		So(err, ShouldBeNil)
+		So(testMarshal(ct, NewCacheMarshaler(), testStruct{}), ShouldResemble, []string{"na", "na"})
+	})
+
+	Convey("encode", t, func() {
+		type testStruct struct {
+			At  timestamp  `csv:"0+1"`
+			Opt *timestamp `csv:"3+2,omitempty"`
+		}
+
+		ct, err := NewCacheTags[testStruct]()
+		So(err, ShouldBeNil)
+		res := testMarshal(ct, NewCacheMarshaler(), testStruct{
+			At: timestamp(time.Date(2023, 2, 3, 10, 11, 12, 0, time.UTC)),
+		})
+		So(res, ShouldResemble, []string{"2023-02-03", "10:11:12", "", ""})
+	})
+
+	Convey("when invalid", t, func() {
+		type testStruct struct {
+			Prop1 int       `csv:"1+2"`
+			Prop2 timestamp `csv:"3+a"`
+			Prop3 timestamp `csv:"4+-5"`
+			Prop4 timestamp `csv:"6+7,default=now"`
+			Prop5 timestamp `csv:"8+8"`
+			Prop6 timestamp `csv:"cols=date|other"`
+		}
+
+		_, err := NewCacheTags[testStruct](WithHeader("date"))
+		So(err, ShouldBeError, strings.Join([]string{
+			"field Prop1: type int implements neither lib.MultiUnmarshaler nor lib.MultiMarshaler",
+			`field Prop2: invalid column "3+a"`,
+			"field Prop3: negative column -5",
+			"field Prop4: no default value allowed for several columns",
+			"field Prop5: duplicate column 8",
+			`field Prop6: unknown header "other"`,
+		}, "\n"))
+	})
+}
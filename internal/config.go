@@ -0,0 +1,106 @@
+package internal
+
+import "time"
+
+// Config stores the global settings applied to every field
+// (unless overridden by a field tag)
+type Config struct {
+	trueValues  []string
+	falseValues []string
+	nullValues  []string
+	timeLayout  string
+	empty       EmptyPolicy
+	trimSpace   bool
+	header      []string
+	methods     []methodDecl
+	converters  converters
+	factories   factories
+}
+
+// EmptyPolicy defines how an empty value is decoded
+type EmptyPolicy int
+
+const (
+	emptyUnset   EmptyPolicy = iota // the empty string is decoded as any other value
+	EmptyError                      // an error is returned
+	EmptyZero                       // the field is set to its zero value
+	EmptyDefault                    // the field is decoded using its default value
+	EmptyMissing                    // the field is left untouched
+)
+
+// empty policies, by tag name
+var emptyPolicies = map[string]EmptyPolicy{
+	"error":   EmptyError,
+	"zero":    EmptyZero,
+	"default": EmptyDefault,
+	"missing": EmptyMissing,
+}
+
+// Option defines a global setting
+type Option func(*Config)
+
+// newConfig init a config with default values and apply the options
+func newConfig(opts ...Option) Config {
+	cfg := Config{
+		trueValues:  []string{"true", "t", "1"},
+		falseValues: []string{"false", "f", "0"},
+		timeLayout:  time.RFC3339Nano,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// WithBoolValues sets the accepted true and false values (case insensitive)
+// The first value of each list is used when encoding
+func WithBoolValues(trueValues, falseValues []string) Option {
+	return func(cfg *Config) {
+		cfg.trueValues = trueValues
+		cfg.falseValues = falseValues
+	}
+}
+
+// WithNullValues sets the values considered as empty (in addition to the empty string)
+// The first value is used when encoding a nil value
+func WithNullValues(nullValues ...string) Option {
+	return func(cfg *Config) {
+		cfg.nullValues = nullValues
+	}
+}
+
+// WithTimeLayout sets the layout used to decode and encode a time.Time
+func WithTimeLayout(layout string) Option {
+	return func(cfg *Config) {
+		cfg.timeLayout = layout
+	}
+}
+
+// WithTrimSpace trims the leading and trailing white spaces of every value before decoding
+func WithTrimSpace() Option {
+	return func(cfg *Config) {
+		cfg.trimSpace = true
+	}
+}
+
+// WithHeader sets the column headers (see lib.FieldContext)
+func WithHeader(header ...string) Option {
+	return func(cfg *Config) {
+		cfg.header = header
+	}
+}
+
+// headerOf returns the header of the column (empty if not defined)
+func (cfg Config) headerOf(col int) string {
+	if col < len(cfg.header) {
+		return cfg.header[col]
+	}
+	return ""
+}
+
+// WithEmptyPolicy sets the behavior when decoding an empty value
+func WithEmptyPolicy(policy EmptyPolicy) Option {
+	return func(cfg *Config) {
+		cfg.empty = policy
+	}
+}
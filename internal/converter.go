@@ -0,0 +1,89 @@
+package internal
+
+import (
+	"context"
+	"reflect"
+	"sync"
+)
+
+// converter stores the marshal and unmarshal functions of a given type
+type converter struct {
+	typ       reflect.Type
+	marshal   marshaler
+	unmarshal unmarshaler
+}
+
+// converters by type
+type converters map[reflect.Type]*converter
+
+// Global converters (see RegisterConverter)
+var globalConverters = struct {
+	sync.RWMutex
+	converters
+}{converters: make(converters)}
+
+// newConverter wraps the typed functions into a converter
+func newConverter[V any](marshal func(V) (string, error), unmarshal func(string) (V, error)) *converter {
+	if marshal == nil || unmarshal == nil {
+		panic("gocsv: nil converter function")
+	}
+	return &converter{
+		typ: reflect.TypeOf((*V)(nil)).Elem(),
+		marshal: func(_ context.Context, field reflect.Value, _ tag) (string, error) {
+			return marshal(field.Interface().(V))
+		},
+		unmarshal: func(_ context.Context, in string, field reflect.Value, _ tag) error {
+			res, err := unmarshal(in)
+			if err != nil {
+				return err
+			}
+			field.Set(reflect.ValueOf(&res).Elem())
+			return nil
+		},
+	}
+}
+
+// RegisterConverter defines the marshal and unmarshal functions of a type for every decoder and encoder
+// Converters shall be registered before creating the decoders and encoders
+func RegisterConverter[V any](marshal func(V) (string, error), unmarshal func(string) (V, error)) {
+	conv := newConverter(marshal, unmarshal)
+	globalConverters.Lock()
+	defer globalConverters.Unlock()
+	globalConverters.converters[conv.typ] = conv
+}
+
+// WithConverter defines the marshal and unmarshal functions of a type (takes precedence over RegisterConverter)
+func WithConverter[V any](marshal func(V) (string, error), unmarshal func(string) (V, error)) Option {
+	conv := newConverter(marshal, unmarshal)
+	return func(cfg *Config) {
+		if cfg.converters == nil {
+			cfg.converters = make(converters)
+		}
+		cfg.converters[conv.typ] = conv
+	}
+}
+
+// converter finds the converter of the given type: scoped first, then global
+// If not found, the converter of the pointed type is used
+func (cfg Config) converter(typ reflect.Type) *converter {
+	conv := cfg.findConverter(typ)
+	if conv == nil && typ.Kind() == reflect.Ptr {
+		conv = cfg.findConverter(typ.Elem())
+	}
+	return conv
+}
+
+// findConverter finds the converter of the exact given type
+func (cfg Config) findConverter(typ reflect.Type) *converter {
+	if conv, ok := cfg.converters[typ]; ok {
+		return conv
+	}
+	globalConverters.RLock()
+	defer globalConverters.RUnlock()
+	return globalConverters.converters[typ]
+}
+
+// handles reports whether the converter can be used on the given type
+func (c *converter) handles(typ reflect.Type) bool {
+	return c != nil && c.typ == typ
+}
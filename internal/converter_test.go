@@ -0,0 +1,102 @@
+package internal
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// celsius is converted using a global converter
+type celsius float64
+
+func init() {
+	RegisterConverter(
+		func(c celsius) (string, error) {
+			return strconv.FormatFloat(float64(c), 'f', -1, 64) + "°C", nil
+		},
+		func(s string) (celsius, error) {
+			f, err := strconv.ParseFloat(strings.TrimSuffix(s, "°C"), 64)
+			return celsius(f), err
+		},
+	)
+}
+
+func TestConverter(t *testing.T) {
+	type testStruct struct {
+		Temp    celsius    `csv:"0"`
+		TempPtr *celsius   `csv:"1,omitempty"`
+		Date    time.Time  `csv:"2"`
+		URL     *url.URL   `csv:"3,omitempty"`
+		DatePtr *time.Time `csv:"4,omitempty"`
+	}
+
+	opts := []Option{
+		WithConverter(
+			func(tm time.Time) (string, error) { return tm.Format(time.DateOnly), nil },
+			func(s string) (time.Time, error) { return time.Parse(time.DateOnly, s) },
+		),
+		WithConverter(
+			func(u *url.URL) (string, error) { return u.String(), nil },
+			url.Parse,
+		),
+	}
+
+	temp := celsius(-3.5)
+	date := time.Date(2023, 12, 2, 0, 0, 0, 0, time.UTC)
+	link, _ := url.Parse("https://example.com/path")
+	ts := testStruct{
+		Temp:    12.5,
+		TempPtr: &temp,
+		Date:    date,
+		URL:     link,
+		DatePtr: &date,
+	}
+	csv := []string{"12.5°C", "-3.5°C", "2023-12-02", "https://example.com/path", "2023-12-02"}
+
+	Convey("unmarshal", t, func() {
+		ct, err := NewCacheTags[testStruct](opts...)
+		So(err, ShouldBeNil)
+		res := testUnmarshal(ct, NewCacheUnmarshaler(), csv)
+		So(res, ShouldResemble, ts)
+
+		Convey("when empty", func() {
+			res := testUnmarshal(ct, NewCacheUnmarshaler(), []string{"0°C", "", "2023-12-02", "", ""})
+			So(res, ShouldResemble, testStruct{Date: date})
+		})
+
+		Convey("when ko", func() {
+			var res testStruct
+			err := Unmarshal(ct, NewCacheUnmarshaler(), []string{"0°C", "", "2023-12-02T00:00:00Z", "", ""}, &res)
+			So(err, ShouldBeError, `col 2: parsing time "2023-12-02T00:00:00Z": extra text: "T00:00:00Z"`)
+		})
+	})
+
+	Convey("marshal", t, func() {
+		ct, err := NewCacheTags[testStruct](opts...)
+		So(err, ShouldBeNil)
+		res := testMarshal(ct, NewCacheMarshaler(), ts)
+		So(res, ShouldResemble, csv)
+
+		Convey("when empty", func() {
+			res := testMarshal(ct, NewCacheMarshaler(), testStruct{Date: date})
+			So(res, ShouldResemble, []string{"0°C", "", "2023-12-02", "", ""})
+		})
+	})
+
+	Convey("when not scoped", t, func() {
+		ct, err := NewCacheTags[testStruct]()
+		So(err, ShouldBeNil)
+		res := testMarshal(ct, NewCacheMarshaler(), testStruct{Temp: 1, Date: date})
+		So(res, ShouldResemble, []string{"1°C", "", "2023-12-02T00:00:00Z", "", ""})
+	})
+
+	Convey("when nil function", t, func() {
+		So(func() { WithConverter[int](nil, strconv.Atoi) }, ShouldPanicWith, "gocsv: nil converter function")
+		So(func() { WithConverter(func(int) (string, error) { return "", fmt.Errorf("ko") }, nil) }, ShouldPanic)
+	})
+}
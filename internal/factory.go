@@ -0,0 +1,72 @@
+package internal
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// factory allocates the dynamic value of an interface field, depending on the input
+type factory func(string) (reflect.Value, error)
+
+// factories by interface type
+type factories map[reflect.Type]factory
+
+// Global factories (see RegisterFactory)
+var globalFactories = struct {
+	sync.RWMutex
+	factories
+}{factories: make(factories)}
+
+// newFactory wraps the typed function into a factory
+func newFactory[I any](fct func(string) (I, error)) (reflect.Type, factory) {
+	typ := reflect.TypeOf((*I)(nil)).Elem()
+	if typ.Kind() != reflect.Interface {
+		panic(fmt.Sprintf("gocsv: factory type %s is not an interface", typ))
+	}
+	if fct == nil {
+		panic("gocsv: nil factory function")
+	}
+	return typ, func(in string) (reflect.Value, error) {
+		res, err := fct(in)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		value := reflect.ValueOf(&res).Elem()
+		if value.IsNil() || (value.Elem().Kind() == reflect.Ptr && value.Elem().IsNil()) {
+			return reflect.Value{}, fmt.Errorf("no %s allocated for value %q", typ, in)
+		}
+		return value.Elem(), nil
+	}
+}
+
+// RegisterFactory defines the function allocating the dynamic value of an interface, for every decoder
+// The returned value (or pointer) is then unmarshaled using the input
+// Factories shall be registered before creating the decoders
+func RegisterFactory[I any](fct func(string) (I, error)) {
+	typ, f := newFactory(fct)
+	globalFactories.Lock()
+	defer globalFactories.Unlock()
+	globalFactories.factories[typ] = f
+}
+
+// WithFactory defines the function allocating the dynamic value of an interface (takes precedence over RegisterFactory)
+func WithFactory[I any](fct func(string) (I, error)) Option {
+	typ, f := newFactory(fct)
+	return func(cfg *Config) {
+		if cfg.factories == nil {
+			cfg.factories = make(factories)
+		}
+		cfg.factories[typ] = f
+	}
+}
+
+// factory finds the factory of the given interface type: scoped first, then global
+func (cfg Config) factory(typ reflect.Type) factory {
+	if f, ok := cfg.factories[typ]; ok {
+		return f
+	}
+	globalFactories.RLock()
+	defer globalFactories.RUnlock()
+	return globalFactories.factories[typ]
+}
@@ -0,0 +1,96 @@
+package internal
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// number or text factory
+func anyFactory(s string) (any, error) {
+	if s == "?" {
+		return nil, nil
+	}
+	if _, err := strconv.Atoi(s); err == nil {
+		return 0, nil
+	}
+	return &customUnmarshal{}, nil
+}
+
+type stringer int
+
+func (it stringer) String() string {
+	return fmt.Sprintf("#%d", int(it))
+}
+
+func TestInterface(t *testing.T) {
+	Convey("unmarshal", t, func() {
+		type testStruct struct {
+			Value    any          `csv:"0"`
+			ValueNil fmt.Stringer `csv:"1,omitempty"`
+		}
+
+		ct, err := NewCacheTags[testStruct](WithFactory(anyFactory))
+		So(err, ShouldBeNil)
+		cm := NewCacheUnmarshaler()
+		ts1 := testUnmarshal(ct, cm, []string{"42", ""})
+		So(ts1, ShouldResemble, testStruct{Value: 42})
+
+		// Use cache with another type
+		ts2 := testUnmarshal(ct, cm, []string{"text", ""})
+		So(ts2, ShouldResemble, testStruct{Value: &customUnmarshal{private: "text"}})
+
+		ts3 := testUnmarshal(ct, cm, []string{"43", ""})
+		So(ts3, ShouldResemble, testStruct{Value: 43})
+
+		Convey("when no type allocated", func() {
+			var ts testStruct
+			err := Unmarshal(ct, cm, []string{"?", ""}, &ts)
+			So(err, ShouldBeError, `col 0: no interface {} allocated for value "?"`)
+		})
+
+		Convey("when no factory", func() {
+			So(PrepareUnmarshaler(ct, NewCacheUnmarshaler()), ShouldBeError, "field ValueNil: no factory defined for fmt.Stringer")
+
+			var ts testStruct
+			err := Unmarshal(ct, cm, []string{"1", "2"}, &ts)
+			So(err, ShouldBeError, "col 1: no factory defined for fmt.Stringer")
+		})
+	})
+
+	Convey("marshal", t, func() {
+		type testStruct struct {
+			Value    any          `csv:"0"`
+			Stringer fmt.Stringer `csv:"1,omitempty"`
+		}
+
+		ct, err := NewCacheTags[testStruct]()
+		So(err, ShouldBeNil)
+		cm := NewCacheMarshaler()
+		res := testMarshal(ct, cm, testStruct{Value: 42, Stringer: nil})
+		So(res, ShouldResemble, []string{"42", ""})
+
+		// Use cache with another type
+		res = testMarshal(ct, cm, testStruct{Value: customMarshal{private: "text"}, Stringer: stringer(1)})
+		So(res, ShouldResemble, []string{"text", "1"})
+
+		value := 3.5
+		res = testMarshal(ct, cm, testStruct{Value: &value})
+		So(res, ShouldResemble, []string{"3.500000", ""})
+
+		Convey("when nil", func() {
+			_, err := Marshal(ct, cm, testStruct{Value: nil})
+			So(err, ShouldBeError, "col 0: nil value found")
+
+			var ptr *int
+			_, err = Marshal(ct, cm, testStruct{Value: ptr})
+			So(err, ShouldBeError, "col 0: nil value found")
+		})
+	})
+
+	Convey("when not an interface", t, func() {
+		So(func() { WithFactory(strconv.Atoi) }, ShouldPanicWith, "gocsv: factory type int is not an interface")
+	})
+}
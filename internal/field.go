@@ -0,0 +1,71 @@
+package internal
+
+import (
+	"context"
+	"maps"
+	"reflect"
+
+	"github.com/sbiemont/gocsv/lib"
+)
+
+var (
+	fieldMarshalerType   = reflect.TypeOf((*lib.FieldMarshaler)(nil)).Elem()
+	fieldUnmarshalerType = reflect.TypeOf((*lib.FieldUnmarshaler)(nil)).Elem()
+)
+
+// rowKey is the context key of the current row
+type rowKey struct{}
+
+// rowInfo describes the current row
+type rowInfo struct {
+	Row
+	raw []string
+}
+
+// withRow stores the current row in the context
+func withRow(ctx context.Context, row Row, raw []string) context.Context {
+	return context.WithValue(ctx, rowKey{}, rowInfo{Row: row, raw: raw})
+}
+
+// fieldContext describes the field of the current row
+func (t tag) fieldContext(ctx context.Context) lib.FieldContext {
+	row, _ := ctx.Value(rowKey{}).(rowInfo)
+	return lib.FieldContext{
+		Context: ctx,
+		Row:     row.Index,
+		Col:     t.col,
+		Header:  t.header,
+		Field:   t.name,
+		Options: maps.Clone(t.options), // the cached options are not shared
+		Raw:     row.raw,
+	}
+}
+
+// hasFieldContext reports whether the type receives the field context (and so the custom tag options)
+func hasFieldContext(typ reflect.Type) bool {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return reflect.PointerTo(typ).Implements(fieldUnmarshalerType) || receiver(typ, fieldMarshalerType) != nil
+}
+
+// Check for field unmarshaler
+func fieldUnmarshaler(typ reflect.Type, _ tag) unmarshaler {
+	if !reflect.PointerTo(typ).Implements(fieldUnmarshalerType) {
+		return nil
+	}
+	return func(ctx context.Context, in string, field reflect.Value, t tag) error {
+		return field.Addr().Interface().(lib.FieldUnmarshaler).UnmarshalCSVField(t.fieldContext(ctx), in)
+	}
+}
+
+// Check for field marshaler
+func fieldMarshaler(typ reflect.Type, _ tag) marshaler {
+	recv := receiver(typ, fieldMarshalerType)
+	if recv == nil {
+		return nil
+	}
+	return func(ctx context.Context, field reflect.Value, t tag) (string, error) {
+		return recv(field).(lib.FieldMarshaler).MarshalCSVField(t.fieldContext(ctx))
+	}
+}
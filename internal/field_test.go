@@ -0,0 +1,85 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sbiemont/gocsv/lib"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// stamp uses the `format=` tag option as its time layout
+type stamp struct {
+	time.Time
+	desc string
+}
+
+func (it *stamp) UnmarshalCSVField(fc lib.FieldContext, s string) error {
+	tm, err := time.Parse(fc.Options["format"], s)
+	if err != nil {
+		return err
+	}
+	it.Time = tm
+	it.desc = fmt.Sprintf("%s/%s row %d col %d of %s", fc.Field, fc.Header, fc.Row, fc.Col, strings.Join(fc.Raw, ";"))
+	return nil
+}
+
+func (it stamp) MarshalCSVField(fc lib.FieldContext) (string, error) {
+	return fmt.Sprintf("%s (row %d)", it.Format(fc.Options["format"]), fc.Row), nil
+}
+
+// UnmarshalCSV is not used (FieldUnmarshaler is preferred)
+func (it *stamp) UnmarshalCSV(string) error {
+	return fmt.Errorf("not used")
+}
+
+// tamper changes the options it receives
+type tamper string
+
+func (it *tamper) UnmarshalCSVField(fc lib.FieldContext, s string) error {
+	*it = tamper(fc.Options["label"] + ":" + s)
+	fc.Options["label"] = "changed"
+	return nil
+}
+
+func TestFieldContext(t *testing.T) {
+	type testStruct struct {
+		ID int    `csv:"0"`
+		At *stamp `csv:"1,format=2006-01-02"`
+	}
+
+	ct, err := NewCacheTags[testStruct](WithHeader("id", "at"))
+
+	Convey("unmarshal", t, func() {
+		So(err, ShouldBeNil)
+		var ts testStruct
+		err := UnmarshalContext(context.Background(), ct, NewCacheUnmarshaler(), Row{Index: 3}, []string{"1", "2023-02-03"}, &ts)
+		So(err, ShouldBeNil)
+		So(ts.At.Time, ShouldEqual, time.Date(2023, 2, 3, 0, 0, 0, 0, time.UTC))
+		So(ts.At.desc, ShouldEqual, "At/at row 3 col 1 of 1;2023-02-03")
+	})
+
+	Convey("marshal", t, func() {
+		So(err, ShouldBeNil)
+		item := testStruct{ID: 1, At: &stamp{Time: time.Date(2023, 2, 3, 0, 0, 0, 0, time.UTC)}}
+		res, err := MarshalContext(context.Background(), ct, NewCacheMarshaler(), 4, item)
+		So(err, ShouldBeNil)
+		So(res, ShouldResemble, []string{"1", "2023-02-03 (row 4)"})
+	})
+	Convey("options are not shared", t, func() {
+		type tampered struct {
+			Value tamper `csv:"0,label=raw"`
+		}
+		ct, err := NewCacheTags[tampered]()
+		So(err, ShouldBeNil)
+		cu := NewCacheUnmarshaler()
+		for _, in := range []string{"a", "b"} {
+			var item tampered
+			So(Unmarshal(ct, cu, []string{in}, &item), ShouldBeNil)
+			So(item.Value, ShouldEqual, tamper("raw:"+in))
+		}
+	})
+}
@@ -0,0 +1,46 @@
+package internal
+
+import (
+	"reflect"
+
+	"github.com/sbiemont/gocsv/lib"
+)
+
+// Row hooks
+var (
+	beforeUnmarshalerType = reflect.TypeOf((*lib.BeforeUnmarshaler)(nil)).Elem()
+	afterUnmarshalerType  = reflect.TypeOf((*lib.AfterUnmarshaler)(nil)).Elem()
+	beforeMarshalerType   = reflect.TypeOf((*lib.BeforeMarshaler)(nil)).Elem()
+)
+
+// beforeUnmarshal calls the lib.BeforeUnmarshaler hook of the row (if implemented)
+func beforeUnmarshal(val reflect.Value, inputs []string) error {
+	recv := receiver(val.Type(), beforeUnmarshalerType)
+	if recv == nil {
+		return nil
+	}
+	return recv(val).(lib.BeforeUnmarshaler).BeforeUnmarshalCSV(inputs)
+}
+
+// afterUnmarshal calls the lib.AfterUnmarshaler hook of the row (if implemented)
+func afterUnmarshal(val reflect.Value) error {
+	recv := receiver(val.Type(), afterUnmarshalerType)
+	if recv == nil {
+		return nil
+	}
+	return recv(val).(lib.AfterUnmarshaler).AfterUnmarshalCSV()
+}
+
+// beforeMarshal calls the lib.BeforeMarshaler hook of the row (if implemented)
+// The returned value shall be marshaled (a copy may be modified by the hook)
+func beforeMarshal(val reflect.Value) (reflect.Value, error) {
+	switch {
+	case val.Type().Implements(beforeMarshalerType):
+		return val, val.Interface().(lib.BeforeMarshaler).BeforeMarshalCSV()
+	case reflect.PointerTo(val.Type()).Implements(beforeMarshalerType):
+		ptr := addr(val)
+		return ptr.Elem(), ptr.Interface().(lib.BeforeMarshaler).BeforeMarshalCSV()
+	default:
+		return val, nil
+	}
+}
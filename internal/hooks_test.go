@@ -0,0 +1,60 @@
+package internal
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// period validates its dates and computes its duration
+type period struct {
+	Start  int `csv:"0"`
+	End    int `csv:"1"`
+	Length int `csv:"2"`
+	Raw    []string
+}
+
+func (it *period) BeforeUnmarshalCSV(raw []string) error {
+	if len(raw) == 0 {
+		return fmt.Errorf("empty record")
+	}
+	it.Raw = raw
+	return nil
+}
+
+func (it *period) AfterUnmarshalCSV() error {
+	if it.End < it.Start {
+		return fmt.Errorf("end %d before start %d", it.End, it.Start)
+	}
+	return nil
+}
+
+func (it *period) BeforeMarshalCSV() error {
+	it.Length = it.End - it.Start
+	return nil
+}
+
+func TestHooks(t *testing.T) {
+	ct, err := NewCacheTags[period]()
+
+	Convey("unmarshal", t, func() {
+		So(err, ShouldBeNil)
+		ts := testUnmarshal(ct, NewCacheUnmarshaler(), []string{"1", "3", "0"})
+		So(ts, ShouldResemble, period{Start: 1, End: 3, Length: 0, Raw: []string{"1", "3", "0"}})
+
+		var item period
+		err := Unmarshal(ct, NewCacheUnmarshaler(), []string{}, &item)
+		So(err, ShouldBeError, "empty record")
+		err = Unmarshal(ct, NewCacheUnmarshaler(), []string{"3", "1", "0"}, &item)
+		So(err, ShouldBeError, "end 1 before start 3")
+	})
+
+	Convey("marshal", t, func() {
+		So(err, ShouldBeNil)
+		item := period{Start: 1, End: 3}
+		res := testMarshal(ct, NewCacheMarshaler(), item)
+		So(res, ShouldResemble, []string{"1", "3", "2"})
+		So(item.Length, ShouldEqual, 0) // a copy is modified
+	})
+}
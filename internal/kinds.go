@@ -0,0 +1,161 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+)
+
+// Kinds associates each discriminator value with a kind of row (ie. {"H": Header{}, "D": &Detail{}})
+type Kinds map[string]any
+
+// kind stores the mapping and the caches of a kind of row
+type kind struct {
+	code string
+	ptr  bool // the row is stored as a pointer to struct
+	ct   CacheTags[any]
+	cu   CacheUnmarshaler
+	cm   CacheMarshaler
+}
+
+// CacheKinds stores the kinds of row of the interface I, using the discriminator column
+type CacheKinds[I any] struct {
+	col    int
+	byCode map[string]*kind
+	byType map[reflect.Type]*kind
+}
+
+// NewCacheKinds init the cache of each kind of row (each one implementing the interface I)
+func NewCacheKinds[I any](kinds Kinds, col int, opts ...Option) (CacheKinds[I], error) {
+	iface := reflect.TypeOf((*I)(nil)).Elem()
+	if iface.Kind() != reflect.Interface {
+		return CacheKinds[I]{}, fmt.Errorf("kinds type %s is not an interface", iface)
+	}
+	if col < 0 {
+		return CacheKinds[I]{}, fmt.Errorf("invalid discriminator column %d", col)
+	}
+
+	cache := CacheKinds[I]{
+		col:    col,
+		byCode: make(map[string]*kind),
+		byType: make(map[reflect.Type]*kind),
+	}
+	for _, code := range sortedCodes(kinds) {
+		// The kind shall be a struct (or a pointer to struct) implementing I
+		typ := reflect.TypeOf(kinds[code])
+		if typ == nil {
+			return CacheKinds[I]{}, fmt.Errorf("kind %q: nil value", code)
+		}
+		if !typ.Implements(iface) {
+			return CacheKinds[I]{}, fmt.Errorf("kind %q: %s does not implement %s", code, typ, iface)
+		}
+		if other, ok := cache.byType[typ]; ok {
+			return CacheKinds[I]{}, fmt.Errorf("kind %q: %s already used by kind %q", code, typ, other.code)
+		}
+		ptr := typ.Kind() == reflect.Ptr
+		elem := typ
+		if ptr {
+			elem = typ.Elem()
+		}
+		if elem.Kind() != reflect.Struct {
+			return CacheKinds[I]{}, fmt.Errorf("kind %q: %s is not a struct", code, typ)
+		}
+
+		ct, err := newCacheTags[any](elem, opts...)
+		if err != nil {
+			return CacheKinds[I]{}, fmt.Errorf("kind %q: %w", code, err)
+		}
+		if user, ok := ct.columnUser(col); ok {
+			return CacheKinds[I]{}, fmt.Errorf("kind %q: %s uses the discriminator column %d", code, user, col)
+		}
+		k := &kind{
+			code: code,
+			ptr:  ptr,
+			ct:   ct,
+			cu:   NewCacheUnmarshaler(),
+			cm:   NewCacheMarshaler(),
+		}
+		cache.byCode[code] = k
+		cache.byType[typ] = k
+	}
+	return cache, nil
+}
+
+// sortedCodes returns the discriminator values (sorted, for reproducible errors)
+func sortedCodes[V any](kinds map[string]V) []string {
+	codes := make([]string, 0, len(kinds))
+	for code := range kinds {
+		codes = append(codes, code)
+	}
+	slices.Sort(codes)
+	return codes
+}
+
+// PrepareKindsUnmarshaler resolves the unmarshalers of every kind
+func PrepareKindsUnmarshaler[I any](ck CacheKinds[I]) error {
+	for _, code := range sortedCodes(ck.byCode) {
+		k := ck.byCode[code]
+		err := PrepareUnmarshaler(k.ct, k.cu)
+		if err != nil {
+			return fmt.Errorf("kind %q: %w", code, err)
+		}
+	}
+	return nil
+}
+
+// PrepareKindsMarshaler resolves the marshalers of every kind
+func PrepareKindsMarshaler[I any](ck CacheKinds[I]) error {
+	for _, code := range sortedCodes(ck.byCode) {
+		k := ck.byCode[code]
+		err := PrepareMarshaler(k.ct, k.cm)
+		if err != nil {
+			return fmt.Errorf("kind %q: %w", code, err)
+		}
+	}
+	return nil
+}
+
+// UnmarshalKind chooses the kind of row using the discriminator column, then fills a new row
+func UnmarshalKind[I any](ctx context.Context, ck CacheKinds[I], row Row, inputs []string, item *I) error {
+	if ck.col >= len(inputs) {
+		return fmt.Errorf("column %d out of bounds", ck.col)
+	}
+	k, ok := ck.byCode[inputs[ck.col]]
+	if !ok {
+		return fmt.Errorf("col %d: unknown kind %q", ck.col, inputs[ck.col])
+	}
+
+	value := reflect.New(k.ct.typ)
+	err := unmarshalValue(withRow(ctx, row, inputs), k.ct, k.cu, inputs, value.Elem())
+	if err != nil {
+		return fmt.Errorf("kind %q: %w", k.code, err)
+	}
+	if !k.ptr {
+		value = value.Elem()
+	}
+	reflect.ValueOf(item).Elem().Set(value)
+	return nil
+}
+
+// MarshalKind marshals the row using its kind, then writes the discriminator column
+func MarshalKind[I any](ctx context.Context, ck CacheKinds[I], row int, item I) ([]string, error) {
+	value := reflect.ValueOf(item)
+	if !value.IsValid() || (value.Kind() == reflect.Ptr && value.IsNil()) {
+		return nil, fmt.Errorf("nil value found")
+	}
+	k, ok := ck.byType[value.Type()]
+	if !ok {
+		return nil, fmt.Errorf("unknown kind for type %s", value.Type())
+	}
+
+	outputs, err := marshalValue(withRow(ctx, Row{Index: row}, nil), k.ct, k.cm, reflect.Indirect(value))
+	if err != nil {
+		return nil, fmt.Errorf("kind %q: %w", k.code, err)
+	}
+	if ck.col >= len(outputs) {
+		outputs = append(outputs, make([]string, ck.col-len(outputs)+1)...)
+	}
+	outputs[ck.col] = k.code
+	return outputs, nil
+}
@@ -0,0 +1,114 @@
+package internal
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type record interface {
+	isRecord()
+}
+
+type header struct {
+	Date time.Time `csv:"1,layout=2006-01-02"`
+}
+
+func (header) isRecord() {}
+
+type detail struct {
+	ID    int    `csv:"1"`
+	Label string `csv:"2"`
+}
+
+func (*detail) isRecord() {}
+
+type trailer struct {
+	Count int `csv:"1"`
+}
+
+func (trailer) isRecord() {}
+
+func (h header) Year() int {
+	return h.Date.Year()
+}
+
+// span is mapped to several columns
+type span struct {
+	Name fullName `csv:"0+1"`
+}
+
+func (span) isRecord() {}
+
+func TestKinds(t *testing.T) {
+	kinds := Kinds{"H": header{}, "D": &detail{}, "T": trailer{}}
+
+	Convey("unmarshal and marshal", t, func() {
+		ck, err := NewCacheKinds[record](kinds, 0)
+		So(err, ShouldBeNil)
+		So(PrepareKindsUnmarshaler(ck), ShouldBeNil)
+		So(PrepareKindsMarshaler(ck), ShouldBeNil)
+
+		inputs := [][]string{
+			{"H", "2023-02-03"},
+			{"D", "1", "first"},
+			{"D", "2", "second"},
+			{"T", "2"},
+		}
+		expected := []record{
+			header{Date: time.Date(2023, 2, 3, 0, 0, 0, 0, time.UTC)},
+			&detail{ID: 1, Label: "first"},
+			&detail{ID: 2, Label: "second"},
+			trailer{Count: 2},
+		}
+		for i, in := range inputs {
+			var rec record
+			So(UnmarshalKind(context.Background(), ck, Row{}, in, &rec), ShouldBeNil)
+			So(rec, ShouldResemble, expected[i])
+
+			out, err := MarshalKind(context.Background(), ck, 0, rec)
+			So(err, ShouldBeNil)
+			So(out, ShouldResemble, in)
+		}
+	})
+
+	Convey("errors", t, func() {
+		ck, err := NewCacheKinds[record](kinds, 0)
+		So(err, ShouldBeNil)
+
+		var rec record
+		So(UnmarshalKind(context.Background(), ck, Row{}, []string{"X"}, &rec), ShouldBeError, `col 0: unknown kind "X"`)
+		So(UnmarshalKind(context.Background(), ck, Row{}, []string{}, &rec), ShouldBeError, "column 0 out of bounds")
+		So(UnmarshalKind(context.Background(), ck, Row{}, []string{"D", "a"}, &rec), ShouldBeError)
+
+		_, err = MarshalKind[record](context.Background(), ck, 0, nil)
+		So(err, ShouldBeError, "nil value found")
+		type unknown struct{ header }
+		_, err = MarshalKind[record](context.Background(), ck, 0, unknown{})
+		So(err, ShouldBeError, "unknown kind for type internal.unknown")
+
+		_, err = NewCacheKinds[header](kinds, 0)
+		So(err, ShouldBeError, "kinds type internal.header is not an interface")
+		_, err = NewCacheKinds[record](Kinds{"D": detail{}}, 0)
+		So(err, ShouldBeError, `kind "D": internal.detail does not implement internal.record`)
+		_, err = NewCacheKinds[record](Kinds{"H": header{}, "X": header{}}, 0)
+		So(err, ShouldBeError, `kind "X": internal.header already used by kind "H"`)
+		_, err = NewCacheKinds[record](kinds, 2)
+		So(err, ShouldBeError, `kind "D": field Label uses the discriminator column 2`)
+		_, err = NewCacheKinds[record](Kinds{"H": header{}}, 2, WithMethod("2", "Year"))
+		So(err, ShouldBeError, `kind "H": method Year uses the discriminator column 2`)
+		_, err = NewCacheKinds[record](Kinds{"S": span{}}, 1)
+		So(err, ShouldBeError, `kind "S": field Name uses the discriminator column 1`)
+	})
+
+	Convey("discriminator column after the fields", t, func() {
+		ck, err := NewCacheKinds[record](kinds, 3)
+		So(err, ShouldBeNil)
+
+		out, err := MarshalKind[record](context.Background(), ck, 0, trailer{Count: 5})
+		So(err, ShouldBeNil)
+		So(out, ShouldResemble, []string{"", "5", "", "T"})
+	})
+}
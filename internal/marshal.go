@@ -1,145 +1,250 @@
 package internal
 
 import (
+	"context"
 	"encoding"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math/big"
+	"net/netip"
+	"net/url"
 	"reflect"
+	"strconv"
+	"time"
 
 	"github.com/sbiemont/gocsv/lib"
 )
 
 // marshal a reflect value into a string
-type marshaler func(reflect.Value) (string, error)
+type marshaler func(context.Context, reflect.Value, tag) (string, error)
 
-// first check that the value can be marshaled before processing
-type marshalerWithCheck func(reflect.Value) (string, bool, error)
+// first check that the type can be marshaled (returns nil if not)
+type marshalerWithCheck func(reflect.Type, tag) marshaler
 
-// convert to unmarshaler (without the check)
-func (it marshalerWithCheck) toMarshaler() marshaler {
-	return func(v reflect.Value) (string, error) {
-		s, _, err := it(v)
-		return s, err
-	}
-}
-
-// CacheMarshaler store a marshaler for the ith field
-type CacheMarshaler map[int]marshaler
+// CacheMarshaler store a marshaler for the ith field and type
+type CacheMarshaler map[cacheKey]marshaler
 
 // NewCacheMarshaler init a new cache
 func NewCacheMarshaler() CacheMarshaler {
 	return make(CacheMarshaler)
 }
 
-func (c CacheMarshaler) use(col int, v reflect.Value) (string, bool, error) {
-	fctCache, ok := c[col]
-	if ok {
-		s, err := fctCache(v)
-		return s, true, err
+// PrepareMarshaler resolves the marshaler of every field (except interface fields, resolved on each row)
+func PrepareMarshaler[T any](ct CacheTags[T], cm CacheMarshaler) error {
+	var errs []error
+	for _, i := range ct.fields() {
+		if !ct.encoded(i) {
+			continue
+		}
+		tag := ct.tags[i]
+		if len(tag.cols) > 0 {
+			err := prepareColumns(ct.fieldType(i), multiMarshalerType)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("field %s: %w", ct.fieldName(i), err))
+			}
+			continue
+		}
+		typ, ok := tag.valueType(ct.fieldType(i))
+		if !ok {
+			continue
+		}
+		_, err := cm.marshaler(i, typ, tag)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("field %s: %w", ct.fieldName(i), err))
+		}
+	}
+	errs = append(errs, ct.checkJoins()...)
+	for k, m := range ct.methods {
+		typ, ok := m.tag.valueType(m.typ)
+		if !ok {
+			continue
+		}
+		_, err := cm.marshaler(ct.methodKey(k), typ, m.tag)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("method %s: %w", m.name, err))
+		}
 	}
-	return "", false, nil
+	return errors.Join(errs...)
 }
 
-func (c CacheMarshaler) storeWithCheck(col int, v reflect.Value, fct marshalerWithCheck) (string, bool, error) {
-	// Use fct and store it in cache if check is ok
-	s, ok, err := fct(v)
-	switch {
-	case err != nil:
-		return "", false, err
-	case ok:
-		c[col] = fct.toMarshaler()
-		return s, true, nil
-	default:
-		return "", false, nil
+// marshaler returns the marshaler of the ith field and type (resolved once, then cached)
+func (c CacheMarshaler) marshaler(i int, typ reflect.Type, t tag) (marshaler, error) {
+	key := cacheKey{field: i, typ: typ}
+	marshal, ok := c[key]
+	if ok {
+		return marshal, nil
+	}
+	marshal, err := resolveMarshaler(typ, t)
+	if err != nil {
+		return nil, err
 	}
+	c[key] = marshal
+	return marshal, nil
 }
 
-func (c CacheMarshaler) storeWithChecks(col int, v reflect.Value, fcts []marshalerWithCheck) (string, bool, error) {
-	for _, fct := range fcts {
-		s, ok, err := c.storeWithCheck(col, v, fct)
-		if ok || err != nil {
-			return s, ok, err
+// resolveMarshaler chooses the marshaler of a type
+func resolveMarshaler(typ reflect.Type, t tag) (marshaler, error) {
+	// Use registered converter
+	if t.conv.handles(typ) {
+		return t.conv.marshal, nil
+	}
+
+	// Built-in types
+	marshal, ok := marshalersTypeConfig[typ]
+	if ok {
+		return marshal, nil
+	}
+
+	// Ordered marshalers with check
+	for _, check := range marshalersWithCheckConfig {
+		marshal := check(typ, t)
+		if marshal != nil {
+			return marshal, nil
 		}
 	}
-	return "", false, nil
-}
 
-func (c CacheMarshaler) store(col int, v reflect.Value, fct marshaler) (string, error) {
-	// Use fct and store it in cache if check is ok
-	s, err := fct(v)
-	if err != nil {
-		return "", err
+	// Choose the field type
+	k := typ.Kind()
+	marshal, ok = marshalersConfig[k]
+	if ok {
+		return marshal, nil
 	}
-	c[col] = fct
-	return s, nil
+
+	// Fallback on the stringer
+	marshal = stringerMarshaler(typ, t)
+	if marshal == nil {
+		return nil, fmt.Errorf("unknown type %s", k)
+	}
+	return marshal, nil
 }
 
 // Marshal a given struct into a list of csv values
 func Marshal[T any](ct CacheTags[T], cm CacheMarshaler, item T) ([]string, error) {
+	return MarshalContext(context.Background(), ct, cm, 0, item)
+}
+
+// MarshalContext marshals the given struct (the context and the row index are given to lib.FieldMarshaler)
+func MarshalContext[T any](ctx context.Context, ct CacheTags[T], cm CacheMarshaler, row int, item T) ([]string, error) {
+	// If T is a pointer to struct, read the pointed struct
+	val := reflect.ValueOf(item)
+	if val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return nil, fmt.Errorf("nil value found")
+		}
+		val = val.Elem()
+	}
+	return marshalValue(withRow(ctx, Row{Index: row}, nil), ct, cm, val)
+}
+
+// marshalValue reads the fields of the given struct value
+func marshalValue[T any](ctx context.Context, ct CacheTags[T], cm CacheMarshaler, val reflect.Value) ([]string, error) {
 	// Error helper using item column
 	makeErr := func(col int, e error) ([]string, error) {
 		return nil, fmt.Errorf("col %d: %w", col, e)
 	}
 
+	// Call the row hook (ie. computing derived fields)
+	val, err := beforeMarshal(val)
+	if err != nil {
+		return nil, err
+	}
+
 	outputs := make(map[int]string)
-	val := reflect.Indirect(reflect.ValueOf(item))
+	owners := make(map[int]string)           // field name, by column
+	parts := make(map[int]map[string]string) // encoded parts, by column
+	partTags := make(map[int]tag)            // tag of a part, by column
+
+	// Store the output of a column (unless primary, the fields sharing a column shall agree)
+	store := func(col int, name, res string) error {
+		prev, ok := outputs[col]
+		if ok && prev != res {
+			return fmt.Errorf("field %s (%q) conflicts with field %s (%q)", name, res, owners[col], prev)
+		}
+		outputs[col] = res
+		owners[col] = name
+		return nil
+	}
+
 	typ := val.Type()
 	for i := 0; i < typ.NumField(); i++ {
 		// Get "csv" info => parse `csv:"tag0,tag1,..,tagN"`
 		tag, ok := ct.tag(i)
-		if ok {
-			// Fetch tags (the first one is the column)
-			col := tag.col
-			omitEmpty := tag.omitEmpty
-
-			// Fetch current attribute
-			field := val.Field(i)
-
-			// If pointer, controls is nil and omit empty
-			if field.Type().Kind() == reflect.Ptr {
-				isNil := field.IsNil()
-				switch {
-				case isNil && omitEmpty:
-					outputs[col] = ""
-					continue
-				case isNil && !omitEmpty:
-					return makeErr(col, fmt.Errorf("nil value found"))
-				default: // field.IsNil(): false
-					field = field.Elem()
-				}
-			}
+		if !ok {
+			continue
+		}
+		col := tag.col
 
-			// Use cache if filled
-			res, ok, err := cm.use(col, field)
-			switch {
-			case err != nil:
-				return makeErr(col, err)
-			case ok:
-				outputs[col] = res
-				continue
-			}
+		// When several fields share the column, only the primary one is encoded (if defined)
+		if !ct.encoded(i) {
+			continue
+		}
 
-			// Ordered marshalers with check
-			res, ok, err = cm.storeWithChecks(col, field, marshalersWithCheckConfig)
-			switch {
-			case err != nil:
+		// Several columns, using lib.MultiMarshaler
+		if len(tag.cols) > 0 {
+			values, err := tag.marshalColumns(val.Field(i))
+			if err != nil {
 				return makeErr(col, err)
-			case ok:
-				outputs[col] = res
-				continue
 			}
-
-			// Choose the field type
-			k := field.Type().Kind()
-			marshal, ok := marshalersConfig[k]
-			if !ok {
-				return makeErr(col, fmt.Errorf("unknown type %s", k))
+			for k, c := range tag.cols {
+				err = store(c, typ.Field(i).Name, values[k])
+				if err != nil {
+					return makeErr(c, err)
+				}
 			}
-			res, err = cm.store(col, field, marshal)
-			if err != nil {
-				return makeErr(col, err)
+			continue
+		}
+
+		// Marshal the field
+		res, err := cm.marshalTagged(ctx, i, val.Field(i), tag)
+		if err != nil {
+			return makeErr(col, err)
+		}
+
+		// Store the part of the column (joined once every part is encoded)
+		if tag.part != "" {
+			if parts[col] == nil {
+				parts[col] = make(map[string]string)
 			}
-			outputs[col] = res
+			parts[col][tag.part] = res
+			partTags[col] = tag
+			continue
+		}
+
+		err = store(col, typ.Field(i).Name, res)
+		if err != nil {
+			return makeErr(col, err)
+		}
+	}
+
+	// Computed columns
+	for k, m := range ct.methods {
+		col := m.tag.col
+		value, err := m.call(val)
+		if err != nil {
+			return makeErr(col, fmt.Errorf("method %s: %w", m.name, err))
+		}
+		res, err := cm.marshalTagged(ctx, ct.methodKey(k), value, m.tag)
+		if err != nil {
+			return makeErr(col, fmt.Errorf("method %s: %w", m.name, err))
+		}
+		err = store(col, m.name+"()", res)
+		if err != nil {
+			return makeErr(col, err)
+		}
+	}
+
+	// Join the parts of each column
+	for _, col := range sortedKeys(parts) {
+		res, err := ct.joinParts(partTags[col], parts[col])
+		if err != nil {
+			return makeErr(col, err)
+		}
+		prev, ok := outputs[col]
+		if ok && prev != res {
+			return makeErr(col, fmt.Errorf("parts (%q) conflict with field %s (%q)", res, owners[col], prev))
 		}
+		outputs[col] = res
 	}
 
 	// Find max col
@@ -153,7 +258,77 @@ func Marshal[T any](ct CacheTags[T], cm CacheMarshaler, item T) ([]string, error
 	return result, nil
 }
 
+// encoded reports whether the ith field is encoded (not hidden by the primary field of its column)
+func (cache CacheTags[T]) encoded(i int) bool {
+	primary, ok := cache.primaries[cache.tags[i].col]
+	return !ok || primary == i
+}
+
+// marshalTagged marshals the ith field, handling nil and empty values
+func (c CacheMarshaler) marshalTagged(ctx context.Context, i int, field reflect.Value, t tag) (string, error) {
+	// If interface, use the dynamic value (unless converted as is)
+	if field.Type().Kind() == reflect.Interface && !field.IsNil() && !t.conv.handles(field.Type()) {
+		field = field.Elem()
+	}
+
+	// If value, controls is zero and omit empty
+	kind := field.Type().Kind()
+	if kind != reflect.Ptr && kind != reflect.Interface {
+		if t.omitEmpty && isZero(field) {
+			return t.null(), nil
+		}
+		return c.marshalField(ctx, i, field, t)
+	}
+
+	// If pointer (at any level) or interface, controls is nil and omit empty
+	for kind == reflect.Ptr || kind == reflect.Interface {
+		isNil := field.IsNil()
+		switch {
+		case isNil && t.omitEmpty:
+			return t.null(), nil
+		case isNil && !t.omitEmpty:
+			return "", fmt.Errorf("nil value found")
+		case t.conv.handles(field.Type()):
+			return c.marshalField(ctx, i, field, t)
+		}
+		field = field.Elem()
+		kind = field.Type().Kind()
+	}
+	return c.marshalField(ctx, i, field, t)
+}
+
+// marshalField chooses the marshaler of the ith field (using the cache if filled), then normalizes the result
+func (c CacheMarshaler) marshalField(ctx context.Context, i int, field reflect.Value, t tag) (string, error) {
+	marshal, err := c.marshaler(i, field.Type(), t)
+	if err != nil {
+		return "", err
+	}
+	res, err := marshal(ctx, field, t)
+	if err != nil {
+		return "", err
+	}
+	return t.normalize(res, normalizeEncode), nil
+}
+
+var marshalersTypeConfig = map[reflect.Type]marshaler{
+	reflect.TypeOf(time.Time{}):      timeMarshaler,
+	reflect.TypeOf(time.Duration(0)): durationMarshaler,
+	reflect.TypeOf(time.Month(0)):    intMarshaler,
+	reflect.TypeOf(time.Weekday(0)):  intMarshaler,
+	reflect.TypeOf(netip.Addr{}):     addrMarshaler,
+	reflect.TypeOf(netip.Prefix{}):   prefixMarshaler,
+	reflect.TypeOf(url.URL{}):        urlMarshaler,
+	reflect.TypeOf(big.Int{}):        bigIntMarshaler,
+	reflect.TypeOf(big.Float{}):      bigFloatMarshaler,
+	reflect.TypeOf(big.Rat{}):        bigRatMarshaler,
+	reflect.TypeOf(lib.Decimal{}):    decimalMarshaler,
+	reflect.TypeOf(lib.Money{}):      moneyMarshaler,
+}
+
 var marshalersWithCheckConfig = []marshalerWithCheck{
+	jsonMarshaler,
+	binaryMarshaler,
+	fieldMarshaler,
 	csvMarshaler,
 	textMarshaler,
 }
@@ -171,6 +346,8 @@ var marshalersConfig = map[reflect.Kind]marshaler{
 	reflect.Uint32: uintMarshaler,
 	reflect.Uint64: uintMarshaler,
 
+	reflect.Uintptr: uintMarshaler,
+
 	reflect.Float32: floatMarshaler,
 	reflect.Float64: floatMarshaler,
 
@@ -179,47 +356,158 @@ var marshalersConfig = map[reflect.Kind]marshaler{
 	reflect.Bool: boolMarshaler,
 }
 
+// isZero reports whether the field is empty (using lib.Zeroer if implemented)
+func isZero(field reflect.Value) bool {
+	recv := receiver(field.Type(), zeroerType)
+	if recv != nil {
+		return recv(field).(lib.Zeroer).IsZero()
+	}
+	return field.IsZero()
+}
+
+// Check for json marshaler (if enabled by the tag)
+func jsonMarshaler(typ reflect.Type, t tag) marshaler {
+	recv := receiver(typ, jsonMarshalerType)
+	if !t.json || recv == nil {
+		return nil
+	}
+	return func(_ context.Context, field reflect.Value, _ tag) (string, error) {
+		res, err := recv(field).(json.Marshaler).MarshalJSON()
+		return string(res), err
+	}
+}
+
+// Check for binary marshaler (if enabled by the tag)
+func binaryMarshaler(typ reflect.Type, t tag) marshaler {
+	recv := receiver(typ, binaryMarshalerType)
+	if !t.binary || recv == nil {
+		return nil
+	}
+	return func(_ context.Context, field reflect.Value, _ tag) (string, error) {
+		res, err := recv(field).(encoding.BinaryMarshaler).MarshalBinary()
+		return string(res), err
+	}
+}
+
 // Check for csv marshaler
-func csvMarshaler(field reflect.Value) (string, bool, error) {
-	// u, ok := field.Addr().Interface().(Marshaler)
-	u, ok := field.Interface().(lib.Marshaler)
-	if !ok {
-		return "", false, nil
+func csvMarshaler(typ reflect.Type, _ tag) marshaler {
+	recv := receiver(typ, csvMarshalerType)
+	if recv == nil {
+		return nil
+	}
+	return func(_ context.Context, field reflect.Value, _ tag) (string, error) {
+		return recv(field).(lib.Marshaler).MarshalCSV()
 	}
-	res, err := u.MarshalCSV()
-	return res, true, err
 }
 
 // Check for text marshaler
-func textMarshaler(field reflect.Value) (string, bool, error) {
-	// u, ok := field.Addr().Interface().(encoding.TextMarshaler)
-	u, ok := field.Interface().(encoding.TextMarshaler)
-	if !ok {
-		return "", false, nil
+func textMarshaler(typ reflect.Type, _ tag) marshaler {
+	recv := receiver(typ, textMarshalerType)
+	if recv == nil {
+		return nil
+	}
+	return func(_ context.Context, field reflect.Value, _ tag) (string, error) {
+		res, err := recv(field).(encoding.TextMarshaler).MarshalText()
+		return string(res), err
+	}
+}
+
+// Check for stringer (used as a fallback)
+func stringerMarshaler(typ reflect.Type, _ tag) marshaler {
+	recv := receiver(typ, stringerType)
+	if recv == nil {
+		return nil
+	}
+	return func(_ context.Context, field reflect.Value, _ tag) (string, error) {
+		return recv(field).(fmt.Stringer).String(), nil
 	}
-	res, err := u.MarshalText()
-	return string(res), true, err
 }
 
-func intMarshaler(field reflect.Value) (string, error) {
-	return fmt.Sprintf("%d", field.Int()), nil
+func intMarshaler(_ context.Context, field reflect.Value, t tag) (string, error) {
+	return t.formatInt(strconv.FormatInt(field.Int(), t.formatBase())), nil
 }
 
-func uintMarshaler(field reflect.Value) (string, error) {
-	return fmt.Sprintf("%d", field.Uint()), nil
+func uintMarshaler(_ context.Context, field reflect.Value, t tag) (string, error) {
+	return t.formatInt(strconv.FormatUint(field.Uint(), t.formatBase())), nil
 }
 
-func floatMarshaler(field reflect.Value) (string, error) {
+func floatMarshaler(_ context.Context, field reflect.Value, _ tag) (string, error) {
 	return fmt.Sprintf("%f", field.Float()), nil
 }
 
-func stringMarshaler(field reflect.Value) (string, error) {
+func stringMarshaler(_ context.Context, field reflect.Value, _ tag) (string, error) {
 	return field.String(), nil
 }
 
-func boolMarshaler(field reflect.Value) (string, error) {
+func boolMarshaler(_ context.Context, field reflect.Value, t tag) (string, error) {
 	if field.Bool() {
-		return "true", nil
+		return t.trueValues[0], nil
+	}
+	return t.falseValues[0], nil
+}
+
+func timeMarshaler(_ context.Context, field reflect.Value, t tag) (string, error) {
+	return field.Interface().(time.Time).Format(t.layout), nil
+}
+
+func durationMarshaler(_ context.Context, field reflect.Value, _ tag) (string, error) {
+	return time.Duration(field.Int()).String(), nil
+}
+
+func addrMarshaler(_ context.Context, field reflect.Value, _ tag) (string, error) {
+	addr := field.Interface().(netip.Addr)
+	if !addr.IsValid() {
+		return "", nil
+	}
+	return addr.String(), nil
+}
+
+func prefixMarshaler(_ context.Context, field reflect.Value, _ tag) (string, error) {
+	prefix := field.Interface().(netip.Prefix)
+	if !prefix.IsValid() {
+		return "", nil
+	}
+	return prefix.String(), nil
+}
+
+func urlMarshaler(_ context.Context, field reflect.Value, _ tag) (string, error) {
+	u := field.Interface().(url.URL)
+	return u.String(), nil
+}
+
+func bigIntMarshaler(_ context.Context, field reflect.Value, _ tag) (string, error) {
+	i := field.Interface().(big.Int)
+	return i.String(), nil
+}
+
+func bigFloatMarshaler(_ context.Context, field reflect.Value, _ tag) (string, error) {
+	f := field.Interface().(big.Float)
+	return f.Text('f', -1), nil
+}
+
+// Format a big rational as a fraction ("5/4") or as a decimal using the tag decimals ("1.25")
+func bigRatMarshaler(_ context.Context, field reflect.Value, t tag) (string, error) {
+	r := field.Interface().(big.Rat)
+	if t.decimals < 0 {
+		return r.RatString(), nil
+	}
+	return r.FloatString(t.decimals), nil
+}
+
+// Format a decimal, rounded using the tag decimals and rounding mode (if defined)
+func decimalMarshaler(_ context.Context, field reflect.Value, t tag) (string, error) {
+	d := field.Interface().(lib.Decimal)
+	if t.decimals >= 0 {
+		d = d.Round(t.decimals, t.mode)
+	}
+	return d.MarshalCSV()
+}
+
+// Format a money, rounded using the tag decimals and rounding mode (if defined)
+func moneyMarshaler(_ context.Context, field reflect.Value, t tag) (string, error) {
+	m := field.Interface().(lib.Money)
+	if t.decimals >= 0 {
+		m = m.Round(t.decimals, t.mode)
 	}
-	return "false", nil
+	return m.MarshalCSV()
 }
@@ -1,6 +1,9 @@
 package internal
 
 import (
+	"math/big"
+	"net/netip"
+	"net/url"
 	"testing"
 	"time"
 
@@ -16,6 +19,54 @@ func (it customMarshal) MarshalCSV() (string, error) {
 	return it.private, nil
 }
 
+// zeroer defines "-" as its empty value
+type zeroer struct {
+	value string
+}
+
+func (it zeroer) MarshalCSV() (string, error) {
+	return it.value, nil
+}
+
+func (it zeroer) IsZero() bool {
+	return it.value == "-"
+}
+
+// ptrMarshal implements the marshalers using a pointer receiver
+type ptrMarshal struct {
+	value string
+}
+
+func (it *ptrMarshal) MarshalCSV() (string, error) {
+	return "csv:" + it.value, nil
+}
+
+func (it *ptrMarshal) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + it.value + `"`), nil
+}
+
+func (it *ptrMarshal) MarshalBinary() ([]byte, error) {
+	return []byte("bin:" + it.value), nil
+}
+
+// ptrText implements the text marshaler using a pointer receiver
+type ptrText struct {
+	value string
+}
+
+func (it *ptrText) MarshalText() ([]byte, error) {
+	return []byte("text:" + it.value), nil
+}
+
+// onlyStringer only implements fmt.Stringer
+type onlyStringer struct {
+	value string
+}
+
+func (it onlyStringer) String() string {
+	return "str:" + it.value
+}
+
 func testMarshal[T any](ct CacheTags[T], cm CacheMarshaler, item T) []string {
 	res, err := Marshal(ct, cm, item)
 	So(err, ShouldBeNil)
@@ -46,12 +97,102 @@ func TestMarshalCSV(t *testing.T) {
 		So(res, ShouldResemble, []string{
 			"true",
 			"false",
-			"false",
+			"",
 			"true",
 			"",
 		})
 	})
 
+	Convey("boolean values", t, func() {
+		type testStruct struct {
+			Bool1 bool `csv:"0"`
+			Bool2 bool `csv:"1,bool=oui|non"`
+			Bool3 bool `csv:"2,true=yes|y,false=no|n"`
+		}
+
+		ct, err := NewCacheTags[testStruct](WithBoolValues([]string{"1"}, []string{"0"}))
+		So(err, ShouldBeNil)
+		cm := NewCacheMarshaler()
+		res := testMarshal(ct, cm, testStruct{
+			Bool1: true,
+			Bool2: false,
+			Bool3: true,
+		})
+		So(res, ShouldResemble, []string{
+			"1",
+			"non",
+			"yes",
+		})
+	})
+
+	Convey("null values", t, func() {
+		type testStruct struct {
+			Ptr1 *int `csv:"0,omitempty"`
+			Ptr2 *int `csv:"1,omitempty,null=NA|-"`
+			Ptr3 *int `csv:"2,omitempty,null=none"`
+		}
+
+		ct, err := NewCacheTags[testStruct](WithNullValues(`\N`, "NULL"))
+		So(err, ShouldBeNil)
+		cm := NewCacheMarshaler()
+		res := testMarshal(ct, cm, testStruct{})
+		So(res, ShouldResemble, []string{
+			`\N`,
+			"NA",
+			"none",
+		})
+	})
+
+	Convey("omit empty values", t, func() {
+		type testStruct struct {
+			Int     int       `csv:"0,omitempty"`
+			IntNull int       `csv:"1,omitempty,null=NA"`
+			Zero    int       `csv:"2"`
+			Ptr     *int      `csv:"3,omitempty"`
+			Date    lib.Date  `csv:"4,omitempty"`
+			Custom  zeroer    `csv:"5,omitempty"`
+			Time    time.Time `csv:"6,omitempty"`
+		}
+
+		ct, err := NewCacheTags[testStruct]()
+		So(err, ShouldBeNil)
+		cm := NewCacheMarshaler()
+		zero := 0
+		res := testMarshal(ct, cm, testStruct{
+			Ptr:    &zero,
+			Custom: zeroer{value: "-"},
+		})
+		So(res, ShouldResemble, []string{
+			"",
+			"NA",
+			"0",
+			"0",
+			"",
+			"",
+			"",
+		})
+
+		Convey("when not zero", func() {
+			res := testMarshal(ct, cm, testStruct{
+				Int:     1,
+				IntNull: 2,
+				Ptr:     &zero,
+				Date:    lib.Date(time.Date(2023, 12, 2, 0, 0, 0, 0, time.UTC)),
+				Custom:  zeroer{value: "value"},
+				Time:    time.Date(2023, 12, 2, 0, 0, 0, 0, time.UTC),
+			})
+			So(res, ShouldResemble, []string{
+				"1",
+				"2",
+				"0",
+				"0",
+				"2023-12-02",
+				"value",
+				"2023-12-02T00:00:00Z",
+			})
+		})
+	})
+
 	Convey("string", t, func() {
 		type testStruct struct {
 			Str1     string  `csv:"0"`
@@ -113,6 +254,40 @@ func TestMarshalCSV(t *testing.T) {
 		})
 	})
 
+	Convey("integer base", t, func() {
+		type testStruct struct {
+			Dec    int    `csv:"0"`
+			Auto   int    `csv:"1,base=0"`
+			Bin    uint8  `csv:"2,base=2,pad=8"`
+			Oct    int    `csv:"3,base=8,prefix"`
+			Hex    int64  `csv:"4,base=16,prefix,pad=4"`
+			HexRaw uint16 `csv:"5,base=16"`
+			Padded int    `csv:"6,pad=5"`
+		}
+
+		ct, err := NewCacheTags[testStruct]()
+		So(err, ShouldBeNil)
+		cm := NewCacheMarshaler()
+		ts := testMarshal(ct, cm, testStruct{
+			Dec:    755,
+			Auto:   31,
+			Bin:    5,
+			Oct:    15,
+			Hex:    -31,
+			HexRaw: 255,
+			Padded: -42,
+		})
+		So(ts, ShouldResemble, []string{
+			"755",
+			"31",
+			"00000101",
+			"0o17",
+			"-0x001f",
+			"ff",
+			"-00042",
+		})
+	})
+
 	Convey("float", t, func() {
 		type testStruct struct {
 			Flt32  float32  `csv:"0"`
@@ -175,6 +350,104 @@ func TestMarshalCSV(t *testing.T) {
 		})
 	})
 
+	Convey("built-in types", t, func() {
+		type testStruct struct {
+			Time    time.Time     `csv:"0"`
+			Date    time.Time     `csv:"1,layout=2006-01-02"`
+			Dur     time.Duration `csv:"2"`
+			Month   time.Month    `csv:"3"`
+			Weekday time.Weekday  `csv:"4"`
+			Addr    netip.Addr    `csv:"5"`
+			Prefix  netip.Prefix  `csv:"6"`
+			URL     *url.URL      `csv:"7"`
+			Empty   netip.Addr    `csv:"8"`
+		}
+
+		ct, err := NewCacheTags[testStruct]()
+		So(err, ShouldBeNil)
+		cm := NewCacheMarshaler()
+		ts := testMarshal(ct, cm, testStruct{
+			Time:    time.Date(2023, 2, 3, 10, 11, 12, 500000000, time.UTC),
+			Date:    time.Date(2023, 2, 3, 10, 11, 12, 0, time.UTC),
+			Dur:     time.Hour + 2*time.Minute + 3*time.Second,
+			Month:   time.March,
+			Weekday: time.Saturday,
+			Addr:    netip.MustParseAddr("192.168.0.1"),
+			Prefix:  netip.MustParsePrefix("10.0.0.0/8"),
+			URL:     &url.URL{Scheme: "https", Host: "example.com", Path: "/path", RawQuery: "q=1"},
+		})
+		So(ts, ShouldResemble, []string{
+			"2023-02-03T10:11:12.5Z",
+			"2023-02-03",
+			"1h2m3s",
+			"3",
+			"6",
+			"192.168.0.1",
+			"10.0.0.0/8",
+			"https://example.com/path?q=1",
+			"",
+		})
+	})
+
+	Convey("big numbers", t, func() {
+		type testStruct struct {
+			Int      big.Int    `csv:"0"`
+			IntPtr   *big.Int   `csv:"1"`
+			Float    big.Float  `csv:"2"`
+			FloatPtr *big.Float `csv:"3"`
+			Rat1     big.Rat    `csv:"4"`
+			Rat2     *big.Rat   `csv:"5,decimals=2"`
+			IntNil   *big.Int   `csv:"6,omitempty"`
+		}
+
+		ct, err := NewCacheTags[testStruct]()
+		So(err, ShouldBeNil)
+		cm := NewCacheMarshaler()
+		var ts testStruct
+		ts.Int.SetString("123456789012345678901234567890", 10)
+		ts.IntPtr, _ = new(big.Int).SetString("-98765432109876543210", 10)
+		ts.Float.SetPrec(128).SetString("12345678901234567890.5")
+		ts.FloatPtr = big.NewFloat(0.25)
+		ts.Rat1.SetString("5/4")
+		ts.Rat2 = big.NewRat(1, 3)
+		res := testMarshal(ct, cm, ts)
+		So(res, ShouldResemble, []string{
+			"123456789012345678901234567890",
+			"-98765432109876543210",
+			"12345678901234567890.5",
+			"0.25",
+			"5/4",
+			"0.33",
+			"",
+		})
+	})
+
+	Convey("decimals", t, func() {
+		type testStruct struct {
+			Dec1  lib.Decimal  `csv:"0"`
+			Dec2  lib.Decimal  `csv:"1,decimals=2"`
+			Dec3  *lib.Decimal `csv:"2,decimals=1,mode=ToZero"`
+			Money lib.Money    `csv:"3,decimals=2,mode=ToNearestAway"`
+		}
+
+		ct, err := NewCacheTags[testStruct]()
+		So(err, ShouldBeNil)
+		cm := NewCacheMarshaler()
+		dec := lib.NewDecimal(199, 2)
+		res := testMarshal(ct, cm, testStruct{
+			Dec1:  lib.NewDecimal(123456, 3),
+			Dec2:  lib.NewDecimal(123456, 3),
+			Dec3:  &dec,
+			Money: lib.Money{Amount: lib.NewDecimal(12345, 3), Currency: "EUR"},
+		})
+		So(res, ShouldResemble, []string{
+			"123.456",
+			"123.46",
+			"1.9",
+			"12.35 EUR",
+		})
+	})
+
 	Convey("custom struct", t, func() {
 		type testStruct struct {
 			Custom1   customMarshal  `csv:"0"`
@@ -219,8 +492,144 @@ func TestMarshalCSV(t *testing.T) {
 		So(ts, ShouldResemble, []string{
 			"2023-02-03T10:11:12Z",
 			"2023-03-04T00:00:00Z",
-			"0001-01-01T00:00:00Z", // should use ptr
 			"",
+			"",
+		})
+	})
+
+	Convey("method sets", t, func() {
+		type testStruct struct {
+			CSV      ptrMarshal   `csv:"0"`
+			CSVPtr   *ptrMarshal  `csv:"1"`
+			JSON     ptrMarshal   `csv:"2,json"`
+			Binary   ptrMarshal   `csv:"3,binary"`
+			Text     ptrText      `csv:"4"`
+			Stringer onlyStringer `csv:"5"`
+		}
+
+		ct, err := NewCacheTags[testStruct]()
+		So(err, ShouldBeNil)
+		cm := NewCacheMarshaler()
+		So(PrepareMarshaler(ct, cm), ShouldBeNil)
+		ts := testMarshal(ct, cm, testStruct{
+			CSV:      ptrMarshal{value: "a"},
+			CSVPtr:   &ptrMarshal{value: "b"},
+			JSON:     ptrMarshal{value: "c"},
+			Binary:   ptrMarshal{value: "d"},
+			Text:     ptrText{value: "e"},
+			Stringer: onlyStringer{value: "f"},
+		})
+		So(ts, ShouldResemble, []string{
+			"csv:a",
+			"csv:b",
+			`"c"`,
+			"bin:d",
+			"text:e",
+			"str:f",
+		})
+	})
+
+	Convey("unknown type", t, func() {
+		type testStruct struct {
+			Value customUnmarshal `csv:"0"` // decode only
+		}
+
+		ct, err := NewCacheTags[testStruct]()
+		So(err, ShouldBeNil)
+		err = PrepareMarshaler(ct, NewCacheMarshaler())
+		So(err, ShouldBeError, "field Value: unknown type struct")
+	})
+
+	Convey("shared column", t, func() {
+		type testStruct struct {
+			Raw  string   `csv:"0"`
+			Date lib.Date `csv:"0,primary"`
+			Num  int      `csv:"1"`
+			Str  string   `csv:"1"`
+		}
+
+		ct, err := NewCacheTags[testStruct]()
+		So(err, ShouldBeNil)
+		cm := NewCacheMarshaler()
+
+		Convey("when the values agree", func() {
+			ts := testMarshal(ct, cm, testStruct{
+				Raw:  "03/02/2023",
+				Date: lib.Date(time.Date(2023, 2, 3, 0, 0, 0, 0, time.UTC)),
+				Num:  42,
+				Str:  "42",
+			})
+			So(ts, ShouldResemble, []string{"2023-02-03", "42"})
+		})
+
+		Convey("when the values disagree", func() {
+			_, err := Marshal(ct, cm, testStruct{Num: 42, Str: "forty-two"})
+			So(err, ShouldBeError, `col 1: field Str ("forty-two") conflicts with field Num ("42")`)
+		})
+	})
+
+	Convey("shared column with a decode only field", t, func() {
+		type testStruct struct {
+			Raw    string          `csv:"0,primary"`
+			Parsed customUnmarshal `csv:"0"`
+		}
+
+		ct, err := NewCacheTags[testStruct]()
+		So(err, ShouldBeNil)
+		cm := NewCacheMarshaler()
+		So(PrepareMarshaler(ct, cm), ShouldBeNil)
+		So(testMarshal(ct, cm, testStruct{Raw: "raw"}), ShouldResemble, []string{"raw"})
+	})
+
+	Convey("normalization", t, func() {
+		type testStruct struct {
+			Code  string `csv:"0,trim,upper,normalize=encode"`
+			Label string `csv:"1,trim"` // decode only
+		}
+
+		ct, err := NewCacheTags[testStruct]()
+		So(err, ShouldBeNil)
+		cm := NewCacheMarshaler()
+		ts := testMarshal(ct, cm, testStruct{Code: " fr ", Label: " label "})
+		So(ts, ShouldResemble, []string{"FR", " label "})
+	})
+
+	Convey("normalization and null values", t, func() {
+		type testStruct struct {
+			Code *string `csv:"0,lower,normalize=both,omitempty,null=NULL"`
+		}
+
+		ct, err := NewCacheTags[testStruct]()
+		So(err, ShouldBeNil)
+		cm := NewCacheMarshaler()
+		So(testMarshal(ct, cm, testStruct{}), ShouldResemble, []string{"NULL"})
+
+		code := "FR"
+		So(testMarshal(ct, cm, testStruct{Code: &code}), ShouldResemble, []string{"fr"})
+	})
+
+	Convey("pointers", t, func() {
+		type testStruct struct {
+			PP  **int `csv:"0"`
+			Opt **int `csv:"1,omitempty"`
+		}
+
+		ct, err := NewCacheTags[*testStruct]()
+		So(err, ShouldBeNil)
+		cm := NewCacheMarshaler()
+		So(PrepareMarshaler(ct, cm), ShouldBeNil)
+
+		i := 42
+		p := &i
+		var nilPtr *int
+		So(testMarshal(ct, cm, &testStruct{PP: &p, Opt: &nilPtr}), ShouldResemble, []string{"42", ""})
+
+		Convey("when nil", func() {
+			_, err := Marshal(ct, cm, &testStruct{PP: &nilPtr})
+			So(err, ShouldBeError, "col 0: nil value found")
+
+			_, err = Marshal(ct, cm, nil)
+			So(err, ShouldBeError, "nil value found")
 		})
 	})
 }
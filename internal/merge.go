@@ -0,0 +1,128 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"math"
+	"reflect"
+)
+
+// Merging returns a copy of the cache where every empty value leaves its field untouched
+// (used to merge partial updates into existing structs)
+func (cache CacheTags[T]) Merging() CacheTags[T] {
+	merging := cache
+	merging.tags = maps.Clone(cache.tags)
+	for i, t := range merging.tags {
+		t.empty = EmptyMissing
+		merging.tags[i] = t
+	}
+	return merging
+}
+
+// MergeContext unmarshals the non empty values into the existing instance (using a merging cache)
+// It returns the names of the changed fields
+func MergeContext[T any](
+	ctx context.Context, ct CacheTags[T], cm CacheUnmarshaler, row Row, inputs []string, item *T,
+) ([]string, error) {
+	ctx = withRow(ctx, row, inputs)
+	val := ct.structValue(item)
+	err := beforeUnmarshal(val, inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decode into a new struct, then copy the decoded fields (the existing values are never shared)
+	next := reflect.New(ct.typ).Elem()
+	presence, err := unmarshalFields(ctx, ct, cm, inputs, next)
+	if err != nil {
+		return nil, err
+	}
+	if ct.presence >= 0 {
+		val.Field(ct.presence).Set(next.Field(ct.presence))
+	}
+	ct.setMetadata(ctx, val)
+
+	var changed []string
+	for _, i := range ct.fields() {
+		name := ct.fieldName(i)
+		if !presence.IsSet(name) {
+			continue
+		}
+		if !sameValue(val.Field(i), next.Field(i)) {
+			changed = append(changed, name)
+		}
+		val.Field(i).Set(next.Field(i))
+	}
+
+	// Call the row hook (ie. cross-field validation)
+	err = afterUnmarshal(val)
+	if err != nil {
+		return nil, err
+	}
+	return changed, nil
+}
+
+// sameValue reports whether both values are deeply equal (NaN floats being equal)
+func sameValue(a, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.Float32, reflect.Float64:
+		x, y := a.Float(), b.Float()
+		return x == y || (math.IsNaN(x) && math.IsNaN(y))
+	case reflect.Ptr:
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() == b.IsNil()
+		}
+		return sameValue(a.Elem(), b.Elem())
+	}
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
+
+// KeyField returns the index of the key field, that shall be of type K
+func KeyField[K comparable, T any](ct CacheTags[T], name string) (int, error) {
+	for _, i := range ct.fields() {
+		if ct.fieldName(i) != name {
+			continue
+		}
+		keyType := reflect.TypeOf((*K)(nil)).Elem()
+		switch {
+		case keyType.Kind() == reflect.Ptr:
+			return -1, fmt.Errorf("key field %s shall not be a pointer", name)
+		case len(ct.tags[i].cols) > 0:
+			return -1, fmt.Errorf("key field %s uses several columns", name)
+		case ct.fieldType(i) != keyType:
+			return -1, fmt.Errorf("key field %s is %s, not %s", name, ct.fieldType(i), keyType)
+		}
+		return i, nil
+	}
+	return -1, fmt.Errorf("key field %s not found", name)
+}
+
+// UnmarshalKey decodes the key field of the record (the other fields and the row hooks are ignored)
+func UnmarshalKey[K comparable, T any](
+	ctx context.Context, ct CacheTags[T], cm CacheUnmarshaler, row Row, field int, inputs []string,
+) (K, error) {
+	var key K
+	t := ct.tags[field]
+	if t.col >= len(inputs) {
+		return key, fmt.Errorf("column %d out of bounds", t.col)
+	}
+	input := inputs[t.col]
+	if t.part != "" {
+		var err error
+		input, err = ct.cellPart(t, input)
+		if err != nil {
+			return key, fmt.Errorf("col %d: %w", t.col, err)
+		}
+	}
+	input, isNull := t.normalizeInput(input)
+	if isNull {
+		return key, fmt.Errorf("col %d: empty key", t.col)
+	}
+
+	err := cm.unmarshalField(withRow(ctx, row, inputs), field, input, reflect.ValueOf(&key).Elem(), t)
+	if err != nil {
+		return key, fmt.Errorf("col %d: %w", t.col, err)
+	}
+	return key, nil
+}
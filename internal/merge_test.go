@@ -0,0 +1,112 @@
+package internal
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// labels are decoded in place (reusing the backing array)
+type labels []string
+
+func (it *labels) UnmarshalCSV(s string) error {
+	*it = append((*it)[:0], strings.Split(s, "|")...)
+	return nil
+}
+
+// attrs are decoded in place (adding the keys)
+type attrs map[string]string
+
+func (it *attrs) UnmarshalCSV(s string) error {
+	if *it == nil {
+		*it = make(attrs)
+	}
+	k, v, _ := strings.Cut(s, "=")
+	(*it)[k] = v
+	return nil
+}
+
+func TestMerge(t *testing.T) {
+	type testStruct struct {
+		ID    int     `csv:"0"`
+		Name  string  `csv:"1,trim"`
+		Count *int    `csv:"2,omitempty"`
+		Price float64 `csv:"3,default=1"`
+	}
+
+	ct, err := NewCacheTags[testStruct]()
+	merging := ct.Merging()
+	cm := NewCacheUnmarshaler()
+
+	Convey("merge", t, func() {
+		So(err, ShouldBeNil)
+		count := 3
+		item := testStruct{ID: 1, Name: "a", Count: &count, Price: 2.5}
+
+		changed, err := MergeContext(context.Background(), merging, cm, Row{}, []string{"1", " b ", ""}, &item)
+		So(err, ShouldBeNil)
+		So(changed, ShouldResemble, []string{"Name"})
+		So(item, ShouldResemble, testStruct{ID: 1, Name: "b", Count: &count, Price: 2.5})
+
+		changed, err = MergeContext(context.Background(), merging, cm, Row{}, []string{"", "", "4", "3"}, &item)
+		So(err, ShouldBeNil)
+		So(changed, ShouldResemble, []string{"Count", "Price"})
+		So(*item.Count, ShouldEqual, 4)
+		So(count, ShouldEqual, 3)
+
+		_, err = MergeContext(context.Background(), merging, cm, Row{}, []string{"x"}, &item)
+		So(err, ShouldBeError, `col 0: strconv.ParseInt: parsing "x": invalid syntax`)
+	})
+
+	Convey("merge values decoded in place", t, func() {
+		type inPlace struct {
+			Labels labels  `csv:"0"`
+			Attrs  attrs   `csv:"1"`
+			Ratio  float64 `csv:"2"`
+		}
+
+		ct, err := NewCacheTags[inPlace]()
+		So(err, ShouldBeNil)
+		lbl := make(labels, 1, 4)
+		lbl[0] = "a"
+		item := inPlace{Labels: lbl, Attrs: attrs{"k": "v"}, Ratio: math.NaN()}
+
+		changed, err := MergeContext(context.Background(), ct.Merging(), NewCacheUnmarshaler(), Row{}, []string{"b|c", "x=y", "NaN"}, &item)
+		So(err, ShouldBeNil)
+		So(changed, ShouldResemble, []string{"Labels", "Attrs"})
+		So(item.Labels, ShouldResemble, labels{"b", "c"})
+		So(item.Attrs, ShouldResemble, attrs{"x": "y"})
+		So(lbl[:2], ShouldResemble, labels{"a", ""}) // the existing values are not modified
+
+		changed, err = MergeContext(context.Background(), ct.Merging(), NewCacheUnmarshaler(), Row{}, []string{"b|c", "x=y", ""}, &item)
+		So(err, ShouldBeNil)
+		So(changed, ShouldBeNil)
+	})
+
+	Convey("key", t, func() {
+		So(err, ShouldBeNil)
+		field, err := KeyField[string](ct, "Name")
+		So(err, ShouldBeNil)
+		key, err := UnmarshalKey[string](context.Background(), ct, cm, Row{}, field, []string{"", " b "})
+		So(err, ShouldBeNil)
+		So(key, ShouldEqual, "b")
+
+		_, err = UnmarshalKey[string](context.Background(), ct, cm, Row{}, field, []string{"1", "  "})
+		So(err, ShouldBeError, "col 1: empty key")
+		_, err = UnmarshalKey[string](context.Background(), ct, cm, Row{}, field, []string{"1"})
+		So(err, ShouldBeError, "column 1 out of bounds")
+	})
+
+	Convey("invalid key field", t, func() {
+		So(err, ShouldBeNil)
+		_, err := KeyField[string](ct, "Other")
+		So(err, ShouldBeError, "key field Other not found")
+		_, err = KeyField[string](ct, "ID")
+		So(err, ShouldBeError, "key field ID is int, not string")
+		_, err = KeyField[*int](ct, "Count")
+		So(err, ShouldBeError, "key field Count shall not be a pointer")
+	})
+}
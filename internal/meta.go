@@ -0,0 +1,77 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+)
+
+// Row describes where a record comes from
+type Row struct {
+	Index  int    // index of the record
+	Line   int    // physical line of the record (0 if unknown)
+	Source string // name of the source (empty if unknown)
+}
+
+// metadata is the kind of metadata filled in a field (ie. `csv:"@line"`)
+type metadata string
+
+const (
+	metaLine   metadata = "@line"
+	metaRow    metadata = "@row"
+	metaRaw    metadata = "@raw"
+	metaSource metadata = "@source"
+)
+
+var stringsType = reflect.TypeOf([]string(nil))
+
+// isMetadata checks if the csv tag defines a metadata field
+func isMetadata(csvTag string) bool {
+	return len(csvTag) > 0 && csvTag[0] == '@'
+}
+
+// newMetadata checks the metadata field `csv:"@name"`
+func newMetadata(field reflect.StructField, csvTag string) (metadata, error) {
+	if !field.IsExported() {
+		return "", fmt.Errorf("unexported field")
+	}
+	meta := metadata(csvTag)
+	switch meta {
+	case metaLine, metaRow:
+		switch field.Type.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return meta, nil
+		}
+		return "", fmt.Errorf("metadata %s requires an int, got %s", meta, field.Type)
+	case metaRaw:
+		if field.Type != stringsType {
+			return "", fmt.Errorf("metadata %s requires a []string, got %s", meta, field.Type)
+		}
+		return meta, nil
+	case metaSource:
+		if field.Type.Kind() != reflect.String {
+			return "", fmt.Errorf("metadata %s requires a string, got %s", meta, field.Type)
+		}
+		return meta, nil
+	}
+	return "", fmt.Errorf("unknown metadata %q", csvTag)
+}
+
+// setMetadata fills the metadata fields using the current row
+func (ct CacheTags[T]) setMetadata(ctx context.Context, val reflect.Value) {
+	row, _ := ctx.Value(rowKey{}).(rowInfo)
+	for _, i := range sortedKeys(ct.metadata) {
+		field := val.Field(i)
+		switch ct.metadata[i] {
+		case metaLine:
+			field.SetInt(int64(row.Line))
+		case metaRow:
+			field.SetInt(int64(row.Index))
+		case metaRaw:
+			field.Set(reflect.ValueOf(slices.Clone(row.raw)))
+		case metaSource:
+			field.SetString(row.Source)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package internal
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMetadata(t *testing.T) {
+	type testStruct struct {
+		ID     int      `csv:"0"`
+		Name   string   `csv:"1"`
+		Line   int      `csv:"@line"`
+		Row    int64    `csv:"@row"`
+		Raw    []string `csv:"@raw"`
+		Source string   `csv:"@source"`
+	}
+
+	ct, err := NewCacheTags[testStruct]()
+
+	Convey("unmarshal", t, func() {
+		So(err, ShouldBeNil)
+		inputs := []string{"1", "a"}
+		var ts testStruct
+		row := Row{Index: 2, Line: 5, Source: "data.csv"}
+		So(UnmarshalContext(context.Background(), ct, NewCacheUnmarshaler(), row, inputs, &ts), ShouldBeNil)
+		So(ts, ShouldResemble, testStruct{ID: 1, Name: "a", Line: 5, Row: 2, Raw: []string{"1", "a"}, Source: "data.csv"})
+
+		// The raw record is a copy
+		inputs[0] = "2"
+		So(ts.Raw, ShouldResemble, []string{"1", "a"})
+	})
+
+	Convey("marshal ignores metadata", t, func() {
+		So(err, ShouldBeNil)
+		item := testStruct{ID: 1, Name: "a", Line: 5, Row: 2, Raw: []string{"x"}, Source: "data.csv"}
+		res, err := Marshal(ct, NewCacheMarshaler(), item)
+		So(err, ShouldBeNil)
+		So(res, ShouldResemble, []string{"1", "a"})
+	})
+
+	Convey("invalid metadata", t, func() {
+		type invalidStruct struct {
+			Line   string `csv:"@line"`
+			Raw    []int  `csv:"@raw"`
+			Source int    `csv:"@source"`
+			Other  int    `csv:"@other"`
+			row    int    `csv:"@row"`
+		}
+		_, err := NewCacheTags[invalidStruct]()
+		So(err, ShouldBeError, "field Line: metadata @line requires an int, got string\n"+
+			"field Raw: metadata @raw requires a []string, got []int\n"+
+			"field Source: metadata @source requires a string, got int\n"+
+			`field Other: unknown metadata "@other"`+"\n"+
+			"field row: unexported field")
+	})
+}
@@ -0,0 +1,98 @@
+package internal
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// method is a column computed by a method of the struct (only encoded)
+type method struct {
+	name  string
+	index int // index of the method, in the method set of the pointer to struct
+	err   bool
+	typ   reflect.Type
+	tag   tag
+}
+
+// methodDecl declares a method column (see WithMethod)
+type methodDecl struct {
+	csvTag string
+	name   string
+}
+
+// WithMethod encodes the result of a method `func() V` (or `func() (V, error)`) as a column
+// The csv tag defines the column (or its header, see WithHeader) and the options, as for a field
+func WithMethod(csvTag, name string) Option {
+	return func(cfg *Config) {
+		cfg.methods = append(cfg.methods, methodDecl{csvTag: csvTag, name: name})
+	}
+}
+
+// newMethod checks the method of the struct, then parses its tag
+func newMethod(cfg Config, typ reflect.Type, csvTag, name string) (method, error) {
+	m, ok := reflect.PointerTo(typ).MethodByName(name)
+	if !ok {
+		return method{}, fmt.Errorf("method %s not found", name)
+	}
+	mt := m.Type // the receiver is the first input
+	if mt.NumIn() != 1 || mt.NumOut() < 1 || mt.NumOut() > 2 || (mt.NumOut() == 2 && mt.Out(1) != errorType) {
+		return method{}, fmt.Errorf("method %s: func() V or func() (V, error) expected, got %s", name, mt)
+	}
+
+	// The column may be given by its header
+	col, opts, _ := strings.Cut(csvTag, ",")
+	if i := slices.Index(cfg.header, col); i >= 0 {
+		csvTag = strings.TrimSuffix(strconv.Itoa(i)+","+opts, ",")
+	}
+
+	field := reflect.StructField{Name: name, Type: mt.Out(0)}
+	t, err := newTag(cfg, field, csvTag)
+	if err != nil {
+		return method{}, fmt.Errorf("method %s: %w", name, err)
+	}
+	if len(t.cols) > 0 || t.part != "" || t.primary {
+		return method{}, fmt.Errorf("method %s: a single column expected", name)
+	}
+	return method{name: name, index: m.Index, err: mt.NumOut() == 2, typ: mt.Out(0), tag: t}, nil
+}
+
+// checkMethods checks that each method has its own column (not used by a field or another method)
+func (cache CacheTags[T]) checkMethods() []error {
+	var errs []error
+	used := make(map[int]string) // field or method, by column
+	for _, i := range cache.fields() {
+		t := cache.tags[i]
+		for _, col := range append([]int{t.col}, t.cols...) {
+			if _, ok := used[col]; !ok {
+				used[col] = "field " + cache.fieldName(i)
+			}
+		}
+	}
+	for _, m := range cache.methods {
+		if other, ok := used[m.tag.col]; ok {
+			errs = append(errs, fmt.Errorf("method %s: column %d already used by %s", m.name, m.tag.col, other))
+			continue
+		}
+		used[m.tag.col] = "method " + m.name
+	}
+	return errs
+}
+
+// call the method on the struct value (using a copy, if not addressable)
+func (m method) call(val reflect.Value) (reflect.Value, error) {
+	out := addr(val).Method(m.index).Call(nil)
+	if m.err && !out[1].IsNil() {
+		return reflect.Value{}, out[1].Interface().(error)
+	}
+	return out[0], nil
+}
+
+// methodKey returns the cache key index of the kth method (after the fields)
+func (cache CacheTags[T]) methodKey(k int) int {
+	return cache.typ.NumField() + k
+}
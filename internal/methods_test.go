@@ -0,0 +1,123 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type person struct {
+	First string    `csv:"0"`
+	Last  string    `csv:"1"`
+	Birth time.Time `csv:"2,layout=2006-01-02"`
+	_     struct{}  `csv:"3" csvmethod:"FullName"`
+	_     struct{}  `csv:"4,layout=2006" csvmethod:"BirthYear"`
+}
+
+func (p person) FullName() string {
+	return p.First + " " + p.Last
+}
+
+func (p *person) BirthYear() time.Time {
+	return p.Birth
+}
+
+func (p person) Initials() (string, error) {
+	if p.First == "" || p.Last == "" {
+		return "", fmt.Errorf("no name")
+	}
+	return p.First[:1] + p.Last[:1], nil
+}
+
+func (p person) Nickname() *string {
+	return nil
+}
+
+func (p person) Greet(string) string {
+	return ""
+}
+
+// splitName computes a column already decoded by a multi-column field
+type splitName struct {
+	Name fullName `csv:"0+1"`
+	_    struct{} `csv:"1" csvmethod:"Upper"`
+}
+
+func (s splitName) Upper() string {
+	return strings.ToUpper(string(s.Name))
+}
+
+func TestMethods(t *testing.T) {
+	birth := time.Date(1990, 5, 17, 0, 0, 0, 0, time.UTC)
+
+	Convey("marshal", t, func() {
+		ct, err := NewCacheTags[person](WithMethod("initials,lower,normalize=encode", "Initials"), WithHeader("first", "last", "birth", "name", "year", "initials"))
+		So(err, ShouldBeNil)
+		cm := NewCacheMarshaler()
+		So(PrepareMarshaler(ct, cm), ShouldBeNil)
+
+		res, err := Marshal(ct, cm, person{First: "John", Last: "Doe", Birth: birth})
+		So(err, ShouldBeNil)
+		So(res, ShouldResemble, []string{"John", "Doe", "1990-05-17", "John Doe", "1990", "jd"})
+
+		_, err = Marshal(ct, cm, person{First: "John", Birth: birth})
+		So(err, ShouldBeError, "col 5: method Initials: no name")
+	})
+
+	Convey("nil result", t, func() {
+		ct, err := NewCacheTags[person](WithMethod("5", "Nickname"))
+		So(err, ShouldBeNil)
+		_, err = Marshal(ct, NewCacheMarshaler(), person{})
+		So(err, ShouldBeError, "col 5: method Nickname: nil value found")
+
+		ct, err = NewCacheTags[person](WithMethod("5,omitempty,null=-", "Nickname"))
+		So(err, ShouldBeNil)
+		res, err := Marshal(ct, NewCacheMarshaler(), person{})
+		So(err, ShouldBeNil)
+		So(res[5], ShouldEqual, "-")
+	})
+
+	Convey("overlapping columns", t, func() {
+		_, err := NewCacheTags[person](WithMethod("0", "Initials"), WithMethod("3", "Initials"), WithMethod("5", "Initials"), WithMethod("5", "FullName"))
+		So(err, ShouldBeError, "method Initials: column 0 already used by field First\n"+
+			"method Initials: column 3 already used by method FullName\n"+
+			"method FullName: column 5 already used by method Initials")
+
+		_, err = NewCacheTags[splitName]()
+		So(err, ShouldBeError, "method Upper: column 1 already used by field Name")
+	})
+
+	Convey("csvmethod on a named field", t, func() {
+		type named struct {
+			First string `csv:"0"`
+			Full  string `csv:"1" csvmethod:"FullName"`
+		}
+		_, err := NewCacheTags[named]()
+		So(err, ShouldBeError, "field Full: csvmethod is only allowed on a blank field")
+	})
+
+	Convey("unmarshal ignores methods", t, func() {
+		ct, err := NewCacheTags[person]()
+		So(err, ShouldBeNil)
+		var p person
+		err = Unmarshal(ct, NewCacheUnmarshaler(), []string{"John", "Doe", "1990-05-17", "x", "y"}, &p)
+		So(err, ShouldBeNil)
+		So(p, ShouldResemble, person{First: "John", Last: "Doe", Birth: birth})
+	})
+
+	Convey("invalid methods", t, func() {
+		_, err := NewCacheTags[person](
+			WithMethod("5", "Unknown"),
+			WithMethod("5", "Greet"),
+			WithMethod("5,part=0,sep=-", "FullName"),
+			WithMethod("x", "FullName"),
+		)
+		So(err, ShouldBeError, "method Unknown not found\n"+
+			"method Greet: func() V or func() (V, error) expected, got func(*internal.person, string) string\n"+
+			"method FullName: a single column expected\n"+
+			`method FullName: invalid column "x"`)
+	})
+}
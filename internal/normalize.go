@@ -0,0 +1,79 @@
+package internal
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// normalizer transforms a value before decoding it (or after encoding it, if enabled)
+type normalizer func(string) string
+
+// normalizers, by tag name
+var normalizers = map[string]normalizer{
+	"trim":     strings.TrimSpace,
+	"ltrim":    func(s string) string { return strings.TrimLeftFunc(s, unicode.IsSpace) },
+	"rtrim":    func(s string) string { return strings.TrimRightFunc(s, unicode.IsSpace) },
+	"lower":    strings.ToLower,
+	"upper":    strings.ToUpper,
+	"collapse": collapse,
+	"nfc":      norm.NFC.String,
+}
+
+// normalization directions, by tag name
+var normalizeModes = map[string]normalizeMode{
+	"decode": normalizeDecode,
+	"encode": normalizeEncode,
+	"both":   normalizeDecode | normalizeEncode,
+}
+
+// normalizeMode defines when the normalizers are applied
+type normalizeMode int
+
+const (
+	normalizeDecode normalizeMode = 1 << iota
+	normalizeEncode
+)
+
+// collapse replaces each sequence of white spaces with a single space
+func collapse(s string) string {
+	var sb strings.Builder
+	space := false
+	for _, c := range s {
+		if unicode.IsSpace(c) {
+			space = true
+			continue
+		}
+		if space {
+			sb.WriteByte(' ')
+			space = false
+		}
+		sb.WriteRune(c)
+	}
+	if space {
+		sb.WriteByte(' ')
+	}
+	return sb.String()
+}
+
+// normalize applies the tag normalizers (in order) when the direction is enabled
+func (t tag) normalize(s string, mode normalizeMode) string {
+	if t.normalizeMode&mode == 0 {
+		return s
+	}
+	for _, n := range t.normalizers {
+		s = n(s)
+	}
+	return s
+}
+
+// normalizeInput normalizes the input to be decoded, reporting whether it is considered as empty
+// (the null values are matched before and after the normalization)
+func (t tag) normalizeInput(in string) (string, bool) {
+	if t.isNull(in) {
+		return in, true
+	}
+	in = t.normalize(in, normalizeDecode)
+	return in, t.isNull(in)
+}
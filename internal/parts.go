@@ -0,0 +1,249 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"regexp/syntax"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// splitTag splits the tag options using the commas (an escaped comma `\,` is kept in the option)
+func splitTag(s string) []string {
+	var res []string
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && s[i+1] == ',':
+			sb.WriteByte(',')
+			i++
+		case s[i] == ',':
+			res = append(res, sb.String())
+			sb.Reset()
+		default:
+			sb.WriteByte(s[i])
+		}
+	}
+	return append(res, sb.String())
+}
+
+// newPattern compiles the pattern of a column, declared using `_ struct{} `csv:"7" regex:"..."`
+// The pattern shall be formattable when encoding (see formatPattern)
+func newPattern(csvTag, regex string) (int, *regexp.Regexp, error) {
+	col, err := strconv.Atoi(csvTag)
+	if err != nil || col < 0 {
+		return 0, nil, fmt.Errorf("invalid column %q", csvTag)
+	}
+	re, err := regexp.Compile(regex)
+	if err != nil {
+		return 0, nil, err
+	}
+	prog, _ := syntax.Parse(regex, syntax.Perl) // already compiled
+	err = formatPattern(io.Discard, prog, nil)
+	if err != nil {
+		return 0, nil, fmt.Errorf("pattern %q: %w", regex, err)
+	}
+	return col, re, nil
+}
+
+// checkParts checks the fields reading a part of a column (the separator or the pattern shall be defined)
+func (cache CacheTags[T]) checkParts() []error {
+	var errs []error
+	seps := make(map[int]string) // separator, by column
+	for _, i := range cache.fields() {
+		t := cache.tags[i]
+		if t.part != "" && t.primary {
+			errs = append(errs, fmt.Errorf("field %s: a part cannot be primary", cache.fieldName(i)))
+		}
+		if t.part == "" {
+			if t.sep != "" {
+				errs = append(errs, fmt.Errorf("field %s: no part defined for separator %q", cache.fieldName(i), t.sep))
+			}
+			continue
+		}
+
+		// Using a pattern: the part is a named group
+		re, ok := cache.patterns[t.col]
+		if ok {
+			if re.SubexpIndex(t.part) < 0 {
+				errs = append(errs, fmt.Errorf("field %s: unknown part %q in pattern %q", cache.fieldName(i), t.part, re))
+			}
+			continue
+		}
+
+		// Using a separator: the part is an index
+		idx, err := strconv.Atoi(t.part)
+		switch {
+		case t.sep == "":
+			errs = append(errs, fmt.Errorf("field %s: no separator or pattern defined for part %q", cache.fieldName(i), t.part))
+		case err != nil || idx < 0:
+			errs = append(errs, fmt.Errorf("field %s: invalid part index %q", cache.fieldName(i), t.part))
+		case seps[t.col] != "" && seps[t.col] != t.sep:
+			errs = append(errs, fmt.Errorf("field %s: separator %q differs from %q", cache.fieldName(i), t.sep, seps[t.col]))
+		default:
+			seps[t.col] = t.sep
+		}
+	}
+	return errs
+}
+
+// cellPart returns the part of the input read by the field (a missing part, or a null cell, is empty)
+func (cache CacheTags[T]) cellPart(t tag, input string) (string, error) {
+	if t.isNull(input) {
+		return "", nil
+	}
+
+	// Using a pattern
+	re, ok := cache.patterns[t.col]
+	if ok {
+		match := re.FindStringSubmatch(input)
+		if match == nil {
+			return "", fmt.Errorf("value %q does not match %q", input, re)
+		}
+		return match[re.SubexpIndex(t.part)], nil
+	}
+
+	// Using a separator
+	idx, _ := strconv.Atoi(t.part)
+	parts := strings.Split(input, t.sep)
+	if idx >= len(parts) {
+		return "", nil
+	}
+	return parts[idx], nil
+}
+
+// joinParts builds the cell using the encoded parts (every part shall be defined)
+// When every part is null, a single null value is written
+func (cache CacheTags[T]) joinParts(t tag, parts map[string]string) (string, error) {
+	res, err := cache.formatParts(t, parts)
+	if err != nil {
+		return "", err
+	}
+	for _, value := range parts {
+		if !t.isNull(value) {
+			return res, nil
+		}
+	}
+	return t.null(), nil
+}
+
+// formatParts writes the parts using the pattern or the separator of the column
+func (cache CacheTags[T]) formatParts(t tag, parts map[string]string) (string, error) {
+	// Using a pattern (the optional elements are omitted)
+	re, ok := cache.patterns[t.col]
+	if ok {
+		var sb strings.Builder
+		prog, _ := syntax.Parse(re.String(), syntax.Perl) // already compiled
+		err := formatPattern(&sb, prog, parts)
+		return sb.String(), err
+	}
+
+	// Using a separator (written as is)
+	values := make([]string, len(parts))
+	for idx := range values {
+		value, ok := parts[strconv.Itoa(idx)]
+		if !ok {
+			return "", fmt.Errorf("missing part %d", idx)
+		}
+		values[idx] = value
+	}
+	return strings.Join(values, t.sep), nil
+}
+
+// checkJoins checks that every column split into parts can be rebuilt when encoding
+func (cache CacheTags[T]) checkJoins() []error {
+	tags := make(map[int]tag)                // tag of a part, by column
+	parts := make(map[int]map[string]string) // parts, by column
+	for _, i := range cache.fields() {
+		t := cache.tags[i]
+		if t.part == "" {
+			continue
+		}
+		if parts[t.col] == nil {
+			parts[t.col] = make(map[string]string)
+		}
+		parts[t.col][t.part] = ""
+		tags[t.col] = t
+	}
+
+	var errs []error
+	for _, col := range sortedKeys(parts) {
+		_, err := cache.joinParts(tags[col], parts[col])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("col %d: %w", col, err))
+		}
+	}
+	return errs
+}
+
+// formatPattern writes the shortest value matching the pattern, using the parts as named groups
+// (a nil parts map only checks the pattern)
+func formatPattern(w io.Writer, re *syntax.Regexp, parts map[string]string) error {
+	switch re.Op {
+	case syntax.OpLiteral:
+		_, _ = io.WriteString(w, string(re.Rune))
+	case syntax.OpCharClass:
+		r, ok := classRune(re.Rune)
+		if !ok {
+			return fmt.Errorf("unsupported character class %s outside a named group", re)
+		}
+		_, _ = io.WriteString(w, string(r))
+	case syntax.OpCapture:
+		if re.Name == "" {
+			return formatPattern(w, re.Sub[0], parts)
+		}
+		if parts != nil {
+			value, ok := parts[re.Name]
+			if !ok {
+				return fmt.Errorf("missing part %q", re.Name)
+			}
+			_, _ = io.WriteString(w, value)
+		}
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			err := formatPattern(w, sub, parts)
+			if err != nil {
+				return err
+			}
+		}
+	case syntax.OpAlternate, syntax.OpPlus:
+		return formatPattern(w, re.Sub[0], parts)
+	case syntax.OpRepeat:
+		for i := 0; i < re.Min; i++ {
+			err := formatPattern(w, re.Sub[0], parts)
+			if err != nil {
+				return err
+			}
+		}
+	case syntax.OpStar, syntax.OpQuest, syntax.OpEmptyMatch,
+		syntax.OpBeginLine, syntax.OpEndLine, syntax.OpBeginText, syntax.OpEndText,
+		syntax.OpWordBoundary, syntax.OpNoWordBoundary:
+		// nothing written (shortest match)
+	default:
+		return fmt.Errorf("unsupported expression %s outside a named group", re)
+	}
+	return nil
+}
+
+// classRune chooses the rune written for a character class (a space if allowed, or the first printable rune)
+// A negated class is not supported
+func classRune(ranges []rune) (rune, bool) {
+	if len(ranges) == 0 || ranges[0] == 0 || ranges[len(ranges)-1] == unicode.MaxRune {
+		return 0, false
+	}
+	for k := 0; k < len(ranges); k += 2 {
+		if ranges[k] <= ' ' && ' ' <= ranges[k+1] {
+			return ' ', true
+		}
+	}
+	for k := 0; k < len(ranges); k += 2 {
+		for r := ranges[k]; r <= ranges[k+1]; r++ {
+			if unicode.IsPrint(r) {
+				return r, true
+			}
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,169 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParts(t *testing.T) {
+	Convey("split tag", t, func() {
+		So(splitTag("7,part=0,sep=\\,"), ShouldResemble, []string{"7", "part=0", "sep=,"})
+		So(splitTag(`0,null=\N`), ShouldResemble, []string{"0", `null=\N`})
+		So(splitTag("0"), ShouldResemble, []string{"0"})
+	})
+
+	Convey("using a separator", t, func() {
+		type testStruct struct {
+			Lat  float64 `csv:"0,part=0,sep=\\,,trim"`
+			Lon  float64 `csv:"0,part=1,sep=\\,,trim"`
+			Raw  string  `csv:"0"`
+			Rest string  `csv:"1,part=2,sep=|"`
+		}
+
+		ct, err := NewCacheTags[testStruct]()
+		So(err, ShouldBeNil)
+		ts := testUnmarshal(ct, NewCacheUnmarshaler(), []string{"47.206, -1.516", "a|b"})
+		So(ts, ShouldResemble, testStruct{Lat: 47.206, Lon: -1.516, Raw: "47.206, -1.516", Rest: ""})
+
+		Convey("when a part is missing", func() {
+			So(PrepareMarshaler(ct, NewCacheMarshaler()), ShouldBeError, "col 1: missing part 0")
+			ts.Raw = "47.206000,-1.516000"
+			_, err := Marshal(ct, NewCacheMarshaler(), ts)
+			So(err, ShouldBeError, `col 1: missing part 0`)
+		})
+	})
+
+	Convey("round trip using a separator", t, func() {
+		type testStruct struct {
+			Lat string `csv:"0,part=0,sep=\\, "`
+			Lon string `csv:"0,part=1,sep=\\, "`
+			Raw string `csv:"0"`
+		}
+
+		ct, err := NewCacheTags[testStruct]()
+		So(err, ShouldBeNil)
+		cm := NewCacheMarshaler()
+		So(PrepareMarshaler(ct, cm), ShouldBeNil)
+		ts := testUnmarshal(ct, NewCacheUnmarshaler(), []string{"47.206, -1.516"})
+		So(ts, ShouldResemble, testStruct{Lat: "47.206", Lon: "-1.516", Raw: "47.206, -1.516"})
+		So(testMarshal(ct, cm, ts), ShouldResemble, []string{"47.206, -1.516"})
+	})
+
+	Convey("using a pattern", t, func() {
+		type testStruct struct {
+			_    struct{} `csv:"1" regex:"^(?P<lat>[^,]+),\\s*(?P<lon>.+)$"`
+			Name string   `csv:"0"`
+			Lat  string   `csv:"1,part=lat"`
+			Lon  string   `csv:"1,part=lon"`
+		}
+
+		ct, err := NewCacheTags[testStruct]()
+		So(err, ShouldBeNil)
+		ts := testUnmarshal(ct, NewCacheUnmarshaler(), []string{"Erdre", "47.206, -1.516"})
+		So(ts, ShouldResemble, testStruct{Name: "Erdre", Lat: "47.206", Lon: "-1.516"})
+
+		res := testMarshal(ct, NewCacheMarshaler(), ts)
+		So(res, ShouldResemble, []string{"Erdre", "47.206,-1.516"})
+
+		// Round trip: the shortest matching value is written
+		ts = testUnmarshal(ct, NewCacheUnmarshaler(), res)
+		So(ts, ShouldResemble, testStruct{Name: "Erdre", Lat: "47.206", Lon: "-1.516"})
+
+		var item testStruct
+		err = Unmarshal(ct, NewCacheUnmarshaler(), []string{"Erdre", "47.206"}, &item)
+		So(err, ShouldBeError, `col 1: value "47.206" does not match "^(?P<lat>[^,]+),\\s*(?P<lon>.+)$"`)
+	})
+
+	Convey("round trip using a pattern", t, func() {
+		type testStruct struct {
+			_    struct{} `csv:"0" regex:"^(?P<code>[A-Z]{2})[-_](?:x|y)?\\s+(?P<num>\\d+)(?:/(?P<ext>\\d+))?$"`
+			Code string   `csv:"0,part=code"`
+			Num  int      `csv:"0,part=num"`
+		}
+
+		ct, err := NewCacheTags[testStruct]()
+		So(err, ShouldBeNil)
+		cm := NewCacheMarshaler()
+		So(PrepareMarshaler(ct, cm), ShouldBeNil)
+		ts := testUnmarshal(ct, NewCacheUnmarshaler(), []string{"FR_x  42/7"})
+		So(ts, ShouldResemble, testStruct{Code: "FR", Num: 42})
+
+		res := testMarshal(ct, cm, ts)
+		So(res, ShouldResemble, []string{"FR- 42"})
+		So(testUnmarshal(ct, NewCacheUnmarshaler(), res), ShouldResemble, ts)
+	})
+
+	Convey("round trip using null values", t, func() {
+		type testStruct struct {
+			_ struct{} `csv:"0" regex:"^(?P<a>[0-9]+)-(?P<b>[0-9]+)$"`
+			A *int     `csv:"0,part=a,omitempty"`
+			B *int     `csv:"0,part=b,omitempty"`
+			C *string  `csv:"1,part=0,sep=/,omitempty"`
+			D *string  `csv:"1,part=1,sep=/,omitempty"`
+		}
+
+		ct, err := NewCacheTags[testStruct](WithNullValues("NA"))
+		So(err, ShouldBeNil)
+		cm := NewCacheMarshaler()
+		So(PrepareMarshaler(ct, cm), ShouldBeNil)
+		ts := testUnmarshal(ct, NewCacheUnmarshaler(), []string{"NA", "NA"})
+		So(ts, ShouldResemble, testStruct{})
+
+		res := testMarshal(ct, cm, ts)
+		So(res, ShouldResemble, []string{"NA", "NA"})
+		So(testUnmarshal(ct, NewCacheUnmarshaler(), res), ShouldResemble, ts)
+
+		// A single null part is written as is
+		d := "x"
+		So(testMarshal(ct, cm, testStruct{D: &d}), ShouldResemble, []string{"NA", "NA/x"})
+	})
+
+	Convey("when the pattern cannot be written", t, func() {
+		type missingPart struct {
+			_    struct{} `csv:"0" regex:"^(?P<a>\\w+)-(?P<b>\\w+)$"`
+			Prop string   `csv:"0,part=a"`
+		}
+		ct, err := NewCacheTags[missingPart]()
+		So(err, ShouldBeNil)
+		So(PrepareMarshaler(ct, NewCacheMarshaler()), ShouldBeError, `col 0: missing part "b"`)
+
+		type negated struct {
+			_    struct{} `csv:"0" regex:"^(?P<a>\\w+)[^-](?P<b>\\w+)$"`
+			Prop string   `csv:"0,part=a"`
+		}
+		_, err = NewCacheTags[negated]()
+		So(err, ShouldBeError, `field _: pattern "^(?P<a>\\w+)[^-](?P<b>\\w+)$": unsupported character class [^\-] outside a named group`+"\n"+
+			`field Prop: no separator or pattern defined for part "a"`)
+
+		type anyChar struct {
+			_    struct{} `csv:"0" regex:"^(?P<a>\\w+).(?P<b>\\w+)$"`
+			Prop string   `csv:"0,part=a"`
+		}
+		_, err = NewCacheTags[anyChar]()
+		So(err, ShouldBeError, `field _: pattern "^(?P<a>\\w+).(?P<b>\\w+)$": unsupported expression (?-s:.) outside a named group`+"\n"+
+			`field Prop: no separator or pattern defined for part "a"`)
+	})
+
+	Convey("when invalid", t, func() {
+		type testStruct struct {
+			_     struct{} `csv:"0" regex:"(?P<a>.*)"`
+			Prop1 string   `csv:"0,part=b"`
+			Prop2 string   `csv:"1,part=0"`
+			Prop3 string   `csv:"2,part=x,sep=;"`
+			Prop4 string   `csv:"3,sep=;"`
+			Prop5 string   `csv:"4,part=0,sep=;"`
+			Prop6 string   `csv:"4,part=1,sep=|"`
+		}
+
+		_, err := NewCacheTags[testStruct]()
+		So(err, ShouldBeError, strings.Join([]string{
+			`field Prop1: unknown part "b" in pattern "(?P<a>.*)"`,
+			`field Prop2: no separator or pattern defined for part "0"`,
+			`field Prop3: invalid part index "x"`,
+			`field Prop4: no part defined for separator ";"`,
+			`field Prop6: separator "|" differs from ";"`,
+		}, "\n"))
+	})
+}
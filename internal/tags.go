@@ -1,60 +1,515 @@
 package internal
 
 import (
+	"errors"
+	"fmt"
+	"math/big"
 	"reflect"
+	"regexp"
 	"slices"
 	"strconv"
 	"strings"
+
+	"github.com/sbiemont/gocsv/lib"
 )
 
 type tag struct {
-	col       int
-	omitEmpty bool
+	name          string            // struct field name
+	header        string            // column header (see WithHeader)
+	options       map[string]string // raw options, by name (see lib.FieldContext)
+	col           int
+	omitEmpty     bool
+	primary       bool   // encoded when several fields share the column
+	cols          []int  // columns of a multi-column field (nil if single column)
+	part          string // part of the column (index or pattern group name)
+	sep           string // separator of the parts
+	trueValues    []string
+	falseValues   []string
+	nullValues    []string
+	layout        string
+	json          bool             // use json.Marshaler and json.Unmarshaler
+	binary        bool             // use encoding.BinaryMarshaler and encoding.BinaryUnmarshaler
+	base          int              // integer base (0 to use the prefix)
+	prefix        bool             // integer base prefix ("0b", "0o", "0x")
+	pad           int              // integer zero-padding width
+	prec          uint             // big.Float precision
+	mode          big.RoundingMode // big.Float and lib.Decimal rounding mode
+	decimals      int              // big.Rat and lib.Decimal decimals (-1 if not defined)
+	empty         EmptyPolicy
+	defaultVal    string
+	normalizers   []normalizer  // applied in order (see normalize)
+	normalizeMode normalizeMode // decode only by default
+	conv          *converter
+	factory       factory
 }
 
 // CacheTags stores the tag data for the ith field
-type CacheTags[T any] map[int]tag
+type CacheTags[T any] struct {
+	typ       reflect.Type
+	tags      map[int]tag
+	primaries map[int]int            // index of the primary field, by column
+	patterns  map[int]*regexp.Regexp // pattern splitting the column into named parts, by column
+	presence  int                    // index of the lib.Presence field (-1 if not defined)
+	metadata  map[int]metadata       // metadata filled on decode (ie. `csv:"@line"`), by field
+	methods   []method               // columns computed by methods, on encode
+}
+
+var presenceType = reflect.TypeOf(lib.Presence{})
 
 // NewCacheTags init the cache using a given type of struct
-func NewCacheTags[T any]() (CacheTags[T], error) {
-	var item T
-	datas := make(map[int]tag)
-	typ := reflect.Indirect(reflect.ValueOf(item)).Type()
+// Every mapping problem is reported at once
+func NewCacheTags[T any](opts ...Option) (CacheTags[T], error) {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	if typ.Kind() == reflect.Ptr && typ.Elem().Kind() == reflect.Struct {
+		typ = typ.Elem() // T may be a pointer to struct
+	}
+	return newCacheTags[T](typ, opts...)
+}
+
+// newCacheTags init the cache using the given struct type
+func newCacheTags[T any](typ reflect.Type, opts ...Option) (CacheTags[T], error) {
+	if typ.Kind() != reflect.Struct {
+		return CacheTags[T]{}, fmt.Errorf("type %s is not a struct", typ)
+	}
+
+	cfg := newConfig(opts...)
+	cache := CacheTags[T]{
+		typ:       typ,
+		tags:      make(map[int]tag),
+		primaries: make(map[int]int),
+		patterns:  make(map[int]*regexp.Regexp),
+		presence:  -1,
+		metadata:  make(map[int]metadata),
+	}
+	var errs []error
 	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+
+		// Store the presence field
+		if field.Type == presenceType {
+			if !field.IsExported() {
+				errs = append(errs, fmt.Errorf("field %s: unexported field", field.Name))
+			}
+			cache.presence = i
+			continue
+		}
+
 		// Get "csv" info => parse `csv:"tag0,tag1,..,tagN"`
-		csvTag, ok := typ.Field(i).Tag.Lookup("csv")
+		csvTag, ok := field.Tag.Lookup("csv")
+		if !ok {
+			continue
+		}
+
+		// Store the metadata fields (not mapped to any column)
+		if isMetadata(csvTag) {
+			meta, err := newMetadata(field, csvTag)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("field %s: %w", field.Name, err))
+				continue
+			}
+			cache.metadata[i] = meta
+			continue
+		}
+
+		// Store the method computing a column `csvmethod:"Name"`
+		name, ok := field.Tag.Lookup("csvmethod")
 		if ok {
-			// Fetch tags (the first one is the position)
-			tags := strings.Split(csvTag, ",")
-			pos, err := strconv.Atoi(tags[0])
+			if field.Name != "_" {
+				errs = append(errs, fmt.Errorf("field %s: csvmethod is only allowed on a blank field", field.Name))
+				continue
+			}
+			m, err := newMethod(cfg, typ, csvTag, name)
 			if err != nil {
-				return nil, err
+				errs = append(errs, err)
+				continue
 			}
+			cache.methods = append(cache.methods, m)
+			continue
+		}
 
-			// Out of bounds (omit empty or unknown position)
-			omitEmpty := slices.Contains(tags[1:], "omitempty")
-			datas[i] = tag{
-				col:       pos,
-				omitEmpty: omitEmpty,
+		// Store the pattern of a column
+		regex, ok := field.Tag.Lookup("regex")
+		if ok {
+			col, re, err := newPattern(csvTag, regex)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("field %s: %w", field.Name, err))
+				continue
+			}
+			if _, ok := cache.patterns[col]; ok {
+				errs = append(errs, fmt.Errorf("field %s: column %d already has a pattern", field.Name, col))
+				continue
 			}
+			cache.patterns[col] = re
+			continue
+		}
+
+		t, err := newTag(cfg, field, csvTag)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("field %s: %w", field.Name, err))
+			continue
+		}
+		if other, ok := cache.primaries[t.col]; ok && t.primary {
+			errs = append(errs, fmt.Errorf("field %s: column %d already has the primary field %s", field.Name, t.col, typ.Field(other).Name))
+			continue
+		}
+		if t.primary {
+			cache.primaries[t.col] = i
+		}
+		cache.tags[i] = t
+	}
+	for _, decl := range cfg.methods {
+		m, err := newMethod(cfg, typ, decl.csvTag, decl.name)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		cache.methods = append(cache.methods, m)
+	}
+	errs = append(errs, cache.checkMethods()...)
+	errs = append(errs, cache.checkParts()...)
+	if len(errs) > 0 {
+		return CacheTags[T]{}, errors.Join(errs...)
+	}
+	return cache, nil
+}
+
+// newTag parses the tag of a field `csv:"tag0,tag1,..,tagN"` (the first one is the column)
+func newTag(cfg Config, field reflect.StructField, csvTag string) (tag, error) {
+	if !field.IsExported() {
+		return tag{}, fmt.Errorf("unexported field")
+	}
+	tags := splitTag(csvTag)
+	cols, err := parseColumns(cfg, tags[0])
+	if err != nil {
+		return tag{}, err
+	}
+
+	t := tag{
+		name:          field.Name,
+		header:        cfg.headerOf(cols[0]),
+		options:       make(map[string]string),
+		col:           cols[0],
+		trueValues:    cfg.trueValues,
+		falseValues:   cfg.falseValues,
+		nullValues:    cfg.nullValues,
+		layout:        cfg.timeLayout,
+		base:          10,
+		decimals:      -1,
+		normalizeMode: normalizeDecode,
+		conv:          cfg.converter(field.Type),
+		factory:       cfg.factory(field.Type),
+	}
+	if cfg.trimSpace {
+		t.normalizers = []normalizer{strings.TrimSpace}
+	}
+	custom := hasFieldContext(field.Type) // custom options are given to the field (see lib.FieldContext)
+	for _, opt := range tags[1:] {
+		err := t.parseOption(opt, custom)
+		if err != nil {
+			return tag{}, err
+		}
+	}
+	err = t.check(cfg)
+	if err != nil {
+		return tag{}, err
+	}
+
+	// Several columns: lib.MultiUnmarshaler or lib.MultiMarshaler shall be implemented
+	if len(cols) > 1 {
+		t.cols = cols
+		if opt, ok := t.boolOption(); ok {
+			return tag{}, fmt.Errorf("option %q is only allowed on a bool field", opt)
+		}
+		return t, t.checkColumns(field.Type)
+	}
+
+	// The type shall be supported at least in one direction (see PrepareUnmarshaler and PrepareMarshaler)
+	typ, ok := t.valueType(field.Type)
+	if ok {
+		unmarshal, errU := resolveUnmarshaler(typ, t)
+		marshal, errM := resolveMarshaler(typ, t)
+		if errU != nil && errM != nil {
+			return tag{}, errU
+		}
+
+		// The bool options are only used by the bool unmarshaler and marshaler
+		opt, ok := t.boolOption()
+		if ok && !sameFunc(unmarshal, boolUnmarshaler) && !sameFunc(marshal, boolMarshaler) {
+			return tag{}, fmt.Errorf("option %q is only allowed on a bool field", opt)
+		}
+	}
+	return t, nil
+}
+
+// boolOption returns the first bool option of the tag (if any)
+func (t tag) boolOption() (string, bool) {
+	for _, name := range []string{"bool", "true", "false"} {
+		if _, ok := t.options[name]; ok {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// sameFunc reports whether both functions are the same one
+func sameFunc[F any](f1, f2 F) bool {
+	return reflect.ValueOf(f1).Pointer() == reflect.ValueOf(f2).Pointer()
+}
+
+// rounding modes, by name
+var roundingModes = func() map[string]big.RoundingMode {
+	modes := make(map[string]big.RoundingMode)
+	for mode := big.ToNearestEven; mode <= big.ToPositiveInf; mode++ {
+		modes[mode.String()] = mode
+	}
+	return modes
+}()
+
+// parseOption reads a single tag option (`name` or `name=value`)
+// An unknown option is an error, unless custom options are allowed
+func (t *tag) parseOption(opt string, custom bool) error {
+	name, value, _ := strings.Cut(opt, "=")
+	t.options[name] = value
+	switch name {
+	case "omitempty":
+		t.omitEmpty = true
+	case "primary":
+		t.primary = true
+	case "part": // part=0 or part=name
+		t.part = value
+	case "sep": // sep=;
+		t.sep = value
+	case "bool": // bool=true|false
+		trueValue, falseValue, ok := strings.Cut(value, "|")
+		if !ok || trueValue == "" || falseValue == "" || strings.Contains(falseValue, "|") {
+			return fmt.Errorf("invalid bool option %q", opt)
+		}
+		t.trueValues = []string{trueValue}
+		t.falseValues = []string{falseValue}
+	case "true", "false", "null": // true=value1|value2
+		values := strings.Split(value, "|")
+		if slices.Contains(values, "") {
+			return fmt.Errorf("invalid %s option %q", name, opt)
+		}
+		switch name {
+		case "true":
+			t.trueValues = values
+		case "false":
+			t.falseValues = values
+		default:
+			t.nullValues = values
+		}
+	case "layout": // layout=2006-01-02
+		t.layout = value
+	case "json":
+		t.json = true
+	case "binary":
+		t.binary = true
+	case "base": // base=2|8|10|16|0
+		base, err := strconv.Atoi(value)
+		if err != nil || base == 1 || base < 0 || base > 36 {
+			return fmt.Errorf("invalid base option %q", opt)
+		}
+		t.base = base
+	case "prefix":
+		t.prefix = true
+	case "pad": // pad=5
+		pad, err := strconv.Atoi(value)
+		if err != nil || pad < 0 {
+			return fmt.Errorf("invalid pad option %q", opt)
+		}
+		t.pad = pad
+	case "prec": // prec=256
+		prec, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid prec option %q", opt)
 		}
+		t.prec = uint(prec)
+	case "mode": // mode=ToNearestEven|ToNearestAway|ToZero|AwayFromZero|ToNegativeInf|ToPositiveInf
+		mode, ok := roundingModes[value]
+		if !ok {
+			return fmt.Errorf("invalid mode option %q", opt)
+		}
+		t.mode = mode
+	case "decimals": // decimals=2
+		decimals, err := strconv.Atoi(value)
+		if err != nil || decimals < 0 {
+			return fmt.Errorf("invalid decimals option %q", opt)
+		}
+		t.decimals = decimals
+	case "empty": // empty=error|zero|default|missing
+		policy, ok := emptyPolicies[value]
+		if !ok {
+			return fmt.Errorf("invalid empty option %q", opt)
+		}
+		t.empty = policy
+	case "default": // default=value
+		t.defaultVal = value
+	case "trim", "ltrim", "rtrim", "lower", "upper", "collapse", "nfc":
+		t.normalizers = append(t.normalizers, normalizers[name])
+	case "normalize": // normalize=decode|encode|both
+		mode, ok := normalizeModes[value]
+		if !ok {
+			return fmt.Errorf("invalid normalize option %q", opt)
+		}
+		t.normalizeMode = mode
+	default:
+		if !custom {
+			return fmt.Errorf("unknown option %q", name)
+		}
+	}
+	return nil
+}
+
+// check the tag consistency and resolve the empty policy
+func (t *tag) check(cfg Config) error {
+	if len(t.trueValues) == 0 || len(t.falseValues) == 0 {
+		return fmt.Errorf("empty boolean values")
+	}
+	if t.prefix && basePrefixes[t.base] == "" {
+		return fmt.Errorf("no prefix defined for base %d", t.base)
+	}
+
+	// Empty policy: tag first, then default value, then omitempty, then global config
+	switch {
+	case t.empty != emptyUnset:
+	case t.defaultVal != "":
+		t.empty = EmptyDefault
+	case t.omitEmpty:
+		t.empty = EmptyMissing
+	default:
+		t.empty = cfg.empty
+	}
+	if t.empty == EmptyDefault && t.defaultVal == "" {
+		return fmt.Errorf("no default value defined")
+	}
+	return nil
+}
+
+// isNull reports whether the input is considered as empty
+func (t tag) isNull(in string) bool {
+	return in == "" || slices.Contains(t.nullValues, in)
+}
+
+// null returns the value to be used when encoding an empty value
+func (t tag) null() string {
+	if len(t.nullValues) == 0 {
+		return ""
+	}
+	return t.nullValues[0]
+}
+
+// integer prefixes, by base
+var basePrefixes = map[int]string{
+	2:  "0b",
+	8:  "0o",
+	16: "0x",
+}
+
+// trimPrefix removes the optional integer base prefix (after the sign)
+func (t tag) trimPrefix(in string) string {
+	if !t.prefix {
+		return in
+	}
+	digits := strings.TrimLeft(in, "+-")
+	sign := in[:len(in)-len(digits)]
+	if len(digits) >= 2 && strings.EqualFold(digits[:2], basePrefixes[t.base]) {
+		return sign + digits[2:]
+	}
+	return in
+}
+
+// formatBase returns the base used to encode an integer
+func (t tag) formatBase() int {
+	if t.base == 0 {
+		return 10
 	}
-	return datas, nil
+	return t.base
+}
+
+// formatInt adds the zero-padding and the integer base prefix (after the sign)
+func (t tag) formatInt(s string) string {
+	digits := strings.TrimPrefix(s, "-")
+	sign := s[:len(s)-len(digits)]
+	if len(digits) < t.pad {
+		digits = strings.Repeat("0", t.pad-len(digits)) + digits
+	}
+	if t.prefix {
+		digits = basePrefixes[t.base] + digits
+	}
+	return sign + digits
+}
+
+// valueType returns the type used to resolve the marshalers of a field
+// Pointers are dereferenced, interfaces are resolved on each row (unless converted as is)
+func (t tag) valueType(typ reflect.Type) (reflect.Type, bool) {
+	for typ.Kind() == reflect.Ptr && !t.conv.handles(typ) {
+		typ = typ.Elem()
+	}
+	if typ.Kind() == reflect.Interface && !t.conv.handles(typ) {
+		return nil, false
+	}
+	return typ, true
 }
 
 // tag returns the ith tag (if found)
 func (cache CacheTags[T]) tag(i int) (tag, bool) {
-	t, ok := cache[i]
+	t, ok := cache.tags[i]
 	return t, ok
 }
 
+// fields returns the indexes of the tagged fields (sorted)
+func (cache CacheTags[T]) fields() []int {
+	return sortedKeys(cache.tags)
+}
+
+// sortedKeys returns the keys of the map (sorted)
+func sortedKeys[V any](m map[int]V) []int {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// fieldType returns the type of the ith field
+func (cache CacheTags[T]) fieldType(i int) reflect.Type {
+	return cache.typ.Field(i).Type
+}
+
+// fieldName returns the name of the ith field
+func (cache CacheTags[T]) fieldName(i int) string {
+	return cache.typ.Field(i).Name
+}
+
 // maxCol found in tags
 func (cache CacheTags[T]) maxCol() int {
 	maxCol := -1
-	for _, data := range cache {
-		if data.col > maxCol {
-			maxCol = data.col
+	for _, data := range cache.tags {
+		maxCol = max(maxCol, data.col)
+		for _, col := range data.cols {
+			maxCol = max(maxCol, col)
 		}
 	}
+	for _, m := range cache.methods {
+		maxCol = max(maxCol, m.tag.col)
+	}
 	return maxCol
 }
+
+// columnUser returns the first field (or method) mapped to the column
+func (cache CacheTags[T]) columnUser(col int) (string, bool) {
+	for _, i := range cache.fields() {
+		t := cache.tags[i]
+		if t.col == col || slices.Contains(t.cols, col) {
+			return "field " + cache.fieldName(i), true
+		}
+	}
+	for _, m := range cache.methods {
+		if m.tag.col == col {
+			return "method " + m.name, true
+		}
+	}
+	return "", false
+}
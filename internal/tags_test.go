@@ -1,8 +1,14 @@
 package internal
 
 import (
+	"math/big"
+	"reflect"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/sbiemont/gocsv/lib"
 	. "github.com/smartystreets/goconvey/convey"
 )
 
@@ -16,18 +22,280 @@ func TestCacheTags(t *testing.T) {
 
 		cache, err := NewCacheTags[custom]()
 		So(err, ShouldBeNil)
-		So(cache, ShouldResemble, CacheTags[custom]{
+		So(cache, ShouldResemble, CacheTags[custom]{typ: reflect.TypeOf(custom{}), primaries: map[int]int{}, patterns: map[int]*regexp.Regexp{}, presence: -1, metadata: map[int]metadata{}, tags: map[int]tag{
 			0: {
-				col:       10,
-				omitEmpty: true,
+				name:          "Prop1",
+				options:       map[string]string{"omitempty": ""},
+				col:           10,
+				omitEmpty:     true,
+				empty:         EmptyMissing,
+				trueValues:    []string{"true", "t", "1"},
+				falseValues:   []string{"false", "f", "0"},
+				layout:        time.RFC3339Nano,
+				base:          10,
+				decimals:      -1,
+				normalizeMode: normalizeDecode,
 			},
 			1: {
-				col:       20,
-				omitEmpty: false,
+				name:          "Prop2",
+				options:       map[string]string{},
+				col:           20,
+				omitEmpty:     false,
+				trueValues:    []string{"true", "t", "1"},
+				falseValues:   []string{"false", "f", "0"},
+				layout:        time.RFC3339Nano,
+				base:          10,
+				decimals:      -1,
+				normalizeMode: normalizeDecode,
 			},
-		})
+		}})
+	})
+
+	Convey("boolean values", t, func() {
+		type custom struct {
+			Prop1 bool `csv:"0,bool=oui|non"`
+			Prop2 bool `csv:"1,true=yes|y,false=no|n"`
+			Prop3 bool `csv:"2"`
+		}
+
+		cache, err := NewCacheTags[custom](WithBoolValues([]string{"on"}, []string{"off"}))
+		So(err, ShouldBeNil)
+		So(cache.tags[0].trueValues, ShouldResemble, []string{"oui"})
+		So(cache.tags[0].falseValues, ShouldResemble, []string{"non"})
+		So(cache.tags[1].trueValues, ShouldResemble, []string{"yes", "y"})
+		So(cache.tags[1].falseValues, ShouldResemble, []string{"no", "n"})
+		So(cache.tags[2].trueValues, ShouldResemble, []string{"on"})
+		So(cache.tags[2].falseValues, ShouldResemble, []string{"off"})
+	})
+
+	Convey("null values", t, func() {
+		type custom struct {
+			Prop1 *int `csv:"0,omitempty,null=NA|-"`
+			Prop2 *int `csv:"1,omitempty"`
+		}
+
+		cache, err := NewCacheTags[custom](WithNullValues("NULL"))
+		So(err, ShouldBeNil)
+		So(cache.tags[0].nullValues, ShouldResemble, []string{"NA", "-"})
+		So(cache.tags[1].nullValues, ShouldResemble, []string{"NULL"})
+	})
+
+	Convey("time layout", t, func() {
+		type custom struct {
+			Prop1 time.Time `csv:"0,layout=2006-01-02"`
+			Prop2 time.Time `csv:"1"`
+		}
+
+		cache, err := NewCacheTags[custom](WithTimeLayout(time.RFC1123))
+		So(err, ShouldBeNil)
+		So(cache.tags[0].layout, ShouldEqual, time.DateOnly)
+		So(cache.tags[1].layout, ShouldEqual, time.RFC1123)
+	})
+
+	Convey("big numbers", t, func() {
+		type custom struct {
+			Prop1 big.Float `csv:"0,prec=256,mode=ToZero"`
+			Prop2 big.Rat   `csv:"1,decimals=2"`
+		}
+
+		cache, err := NewCacheTags[custom]()
+		So(err, ShouldBeNil)
+		So(cache.tags[0].prec, ShouldEqual, 256)
+		So(cache.tags[0].mode, ShouldEqual, big.ToZero)
+		So(cache.tags[1].decimals, ShouldEqual, 2)
+	})
+
+	Convey("encoding", t, func() {
+		type custom struct {
+			Prop1 string `csv:"0,json"`
+			Prop2 string `csv:"1,binary"`
+			Prop3 string `csv:"2"`
+		}
+
+		cache, err := NewCacheTags[custom]()
+		So(err, ShouldBeNil)
+		So(cache.tags[0].json, ShouldBeTrue)
+		So(cache.tags[1].binary, ShouldBeTrue)
+		So(cache.tags[2].json || cache.tags[2].binary, ShouldBeFalse)
+	})
+
+	Convey("normalization", t, func() {
+		type custom struct {
+			Prop1 string `csv:"0,trim,lower"`
+			Prop2 string `csv:"1,upper,normalize=both"`
+			Prop3 string `csv:"2"`
+		}
+
+		cache, err := NewCacheTags[custom](WithTrimSpace())
+		So(err, ShouldBeNil)
+		So(cache.tags[0].normalizers, ShouldHaveLength, 3) // global trim first
+		So(cache.tags[0].normalize(" A b ", normalizeDecode), ShouldEqual, "a b")
+		So(cache.tags[0].normalize(" A b ", normalizeEncode), ShouldEqual, " A b ")
+		So(cache.tags[1].normalize(" a ", normalizeEncode), ShouldEqual, "A")
+		So(cache.tags[2].normalize(" a ", normalizeDecode), ShouldEqual, "a")
+	})
+
+	Convey("empty policy", t, func() {
+		type custom struct {
+			Prop1 int `csv:"0,empty=zero"`
+			Prop2 int `csv:"1,default=42"`
+			Prop3 int `csv:"2,omitempty"`
+			Prop4 int `csv:"3,omitempty,empty=error"`
+			Prop5 int `csv:"4"`
+			Set   lib.Presence
+		}
+
+		cache, err := NewCacheTags[custom](WithEmptyPolicy(EmptyError))
+		So(err, ShouldBeNil)
+		So(cache.presence, ShouldEqual, 5)
+		So(cache.tags[0].empty, ShouldEqual, EmptyZero)
+		So(cache.tags[1].empty, ShouldEqual, EmptyDefault)
+		So(cache.tags[1].defaultVal, ShouldEqual, "42")
+		So(cache.tags[2].empty, ShouldEqual, EmptyMissing)
+		So(cache.tags[3].empty, ShouldEqual, EmptyError)
+		So(cache.tags[4].empty, ShouldEqual, EmptyError)
 	})
 
 	Convey("when ko", t, func() {
+		Convey("when invalid bool option", func() {
+			type custom struct {
+				Prop1 bool `csv:"0,bool=oui"`
+				Prop2 bool `csv:"1,bool=a|b|c"`
+				Prop3 bool `csv:"2,bool=|non"`
+				Prop4 bool `csv:"3,bool=oui|"`
+			}
+			_, err := NewCacheTags[custom]()
+			So(err, ShouldBeError, `field Prop1: invalid bool option "bool=oui"`+"\n"+
+				`field Prop2: invalid bool option "bool=a|b|c"`+"\n"+
+				`field Prop3: invalid bool option "bool=|non"`+"\n"+
+				`field Prop4: invalid bool option "bool=oui|"`)
+		})
+
+		Convey("when empty values in a list", func() {
+			type custom struct {
+				Prop1 bool `csv:"0,true=,false=no"`
+				Prop2 bool `csv:"1,false=no|"`
+				Prop3 *int `csv:"2,null=NA||-"`
+			}
+			_, err := NewCacheTags[custom]()
+			So(err, ShouldBeError, `field Prop1: invalid true option "true="`+"\n"+
+				`field Prop2: invalid false option "false=no|"`+"\n"+
+				`field Prop3: invalid null option "null=NA||-"`)
+		})
+
+		Convey("when bool options on a non bool field", func() {
+			type custom struct {
+				Prop1 lib.Bool `csv:"0,bool=oui|non"`
+				Prop2 string   `csv:"1,true=yes"`
+				Prop3 fullName `csv:"2+3,false=no"`
+				Prop4 *bool    `csv:"4,bool=oui|non"`
+			}
+			_, err := NewCacheTags[custom]()
+			So(err, ShouldBeError, `field Prop1: option "bool" is only allowed on a bool field`+"\n"+
+				`field Prop2: option "true" is only allowed on a bool field`+"\n"+
+				`field Prop3: option "false" is only allowed on a bool field`)
+		})
+
+		Convey("when empty boolean values", func() {
+			type custom struct {
+				Prop1 bool `csv:"0"`
+			}
+			_, err := NewCacheTags[custom](WithBoolValues(nil, []string{"false"}))
+			So(err, ShouldBeError, "field Prop1: empty boolean values")
+		})
+
+		Convey("when invalid integer options", func() {
+			type custom1 struct {
+				Prop1 int `csv:"0,base=1"`
+			}
+			_, err := NewCacheTags[custom1]()
+			So(err, ShouldBeError, `field Prop1: invalid base option "base=1"`)
+
+			type custom2 struct {
+				Prop1 int `csv:"0,pad=-1"`
+			}
+			_, err = NewCacheTags[custom2]()
+			So(err, ShouldBeError, `field Prop1: invalid pad option "pad=-1"`)
+
+			type custom3 struct {
+				Prop1 int `csv:"0,prefix"`
+			}
+			_, err = NewCacheTags[custom3]()
+			So(err, ShouldBeError, "field Prop1: no prefix defined for base 10")
+		})
+
+		Convey("when invalid big options", func() {
+			type custom1 struct {
+				Prop1 big.Float `csv:"0,prec=-1"`
+			}
+			_, err := NewCacheTags[custom1]()
+			So(err, ShouldBeError, `field Prop1: invalid prec option "prec=-1"`)
+
+			type custom2 struct {
+				Prop1 big.Float `csv:"0,mode=Up"`
+			}
+			_, err = NewCacheTags[custom2]()
+			So(err, ShouldBeError, `field Prop1: invalid mode option "mode=Up"`)
+
+			type custom3 struct {
+				Prop1 big.Rat `csv:"0,decimals=two"`
+			}
+			_, err = NewCacheTags[custom3]()
+			So(err, ShouldBeError, `field Prop1: invalid decimals option "decimals=two"`)
+		})
+
+		Convey("when invalid empty option", func() {
+			type custom struct {
+				Prop1 int `csv:"0,empty=none"`
+			}
+			_, err := NewCacheTags[custom]()
+			So(err, ShouldBeError, `field Prop1: invalid empty option "empty=none"`)
+		})
+
+		Convey("when invalid normalize option", func() {
+			type custom struct {
+				Prop1 string `csv:"0,trim,normalize=always"`
+			}
+			_, err := NewCacheTags[custom]()
+			So(err, ShouldBeError, `field Prop1: invalid normalize option "normalize=always"`)
+		})
+
+		Convey("when no default value", func() {
+			type custom struct {
+				Prop1 int `csv:"0,empty=default"`
+			}
+			_, err := NewCacheTags[custom]()
+			So(err, ShouldBeError, "field Prop1: no default value defined")
+		})
+
+		Convey("when the mapping is invalid", func() {
+			type custom struct {
+				Prop1 int            `csv:"-1"`
+				Prop2 int            `csv:"one"`
+				Prop3 int            `csv:"2,primary"`
+				Prop4 string         `csv:"2,primary"`
+				prop5 int            `csv:"5"`
+				Prop6 chan int       `csv:"6"`
+				Prop7 map[string]int `csv:"7"`
+				Prop8 *int           `csv:"8,omitemtpy"`
+				Prop9 string         `csv:"9,layuot=2006"`
+			}
+			_, err := NewCacheTags[custom]()
+			So(err, ShouldBeError, strings.Join([]string{
+				"field Prop1: negative column -1",
+				`field Prop2: invalid column "one"`,
+				"field Prop4: column 2 already has the primary field Prop3",
+				"field prop5: unexported field",
+				"field Prop6: unknown type chan",
+				"field Prop7: unknown type map",
+				`field Prop8: unknown option "omitemtpy"`,
+				`field Prop9: unknown option "layuot"`,
+			}, "\n"))
+		})
+
+		Convey("when not a struct", func() {
+			_, err := NewCacheTags[int]()
+			So(err, ShouldBeError, "type int is not a struct")
+		})
 	})
 }
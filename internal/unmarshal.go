@@ -1,156 +1,326 @@
 package internal
 
 import (
+	"context"
 	"encoding"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"math/big"
+	"net/netip"
+	"net/url"
 	"reflect"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/sbiemont/gocsv/lib"
 )
 
 // unmarshal a string into a reflect value
-type unmarshaler func(string, reflect.Value) error
+type unmarshaler func(context.Context, string, reflect.Value, tag) error
 
-// first check that the string can be unmarshaled before processing
-type unmarshalerWithCheck func(string, reflect.Value) (bool, error)
+// first check that the type can be unmarshaled (returns nil if not)
+type unmarshalerWithCheck func(reflect.Type, tag) unmarshaler
 
-// convert to unmarshaler (without the check)
-func (it unmarshalerWithCheck) toUnmarshaler() unmarshaler {
-	return func(s string, v reflect.Value) error {
-		_, err := it(s, v)
-		return err
-	}
-}
-
-// CacheUnmarshaler is used to store the unmarshaler function for the ith field
-type CacheUnmarshaler map[int]unmarshaler
+// CacheUnmarshaler is used to store the unmarshaler function for the ith field and type
+type CacheUnmarshaler map[cacheKey]unmarshaler
 
 // NewCacheUnmarshaler init a new cache
 func NewCacheUnmarshaler() CacheUnmarshaler {
 	return make(CacheUnmarshaler)
 }
 
-func (c CacheUnmarshaler) storeWithCheck(col int, s string, v reflect.Value, fct unmarshalerWithCheck) (bool, error) {
-	// Use fct and store it in cache if check is ok
-	ok, err := fct(s, v)
-	switch {
-	case err != nil:
-		return false, err
-	case ok:
-		c[col] = fct.toUnmarshaler()
-		return true, nil
-	default:
-		return false, nil
-	}
-}
-
-func (c CacheUnmarshaler) storeWithChecks(
-	col int,
-	s string,
-	v reflect.Value,
-	fcts []unmarshalerWithCheck,
-) (bool, error) {
-	for _, fct := range fcts {
-		ok, err := c.storeWithCheck(col, s, v, fct)
-		if ok || err != nil {
-			return ok, err
+// PrepareUnmarshaler resolves the unmarshaler of every field
+// Interface fields are resolved on each row, but shall have a factory
+func PrepareUnmarshaler[T any](ct CacheTags[T], cm CacheUnmarshaler) error {
+	var errs []error
+	for _, i := range ct.fields() {
+		tag := ct.tags[i]
+		if len(tag.cols) > 0 {
+			err := prepareColumns(ct.fieldType(i), multiUnmarshalerType)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("field %s: %w", ct.fieldName(i), err))
+			}
+			continue
+		}
+		typ, ok := tag.valueType(ct.fieldType(i))
+		if !ok {
+			if tag.factory == nil {
+				errs = append(errs, fmt.Errorf("field %s: no factory defined for %s", ct.fieldName(i), ct.fieldType(i)))
+			}
+			continue
+		}
+		_, err := cm.unmarshaler(i, typ, tag)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("field %s: %w", ct.fieldName(i), err))
 		}
 	}
-	return false, nil
+	return errors.Join(errs...)
 }
 
-func (c CacheUnmarshaler) store(col int, s string, v reflect.Value, fct unmarshaler) error {
-	// Use fct and store it in cache if check is ok
-	err := fct(s, v)
+// unmarshaler returns the unmarshaler of the ith field and type (resolved once, then cached)
+func (c CacheUnmarshaler) unmarshaler(i int, typ reflect.Type, t tag) (unmarshaler, error) {
+	key := cacheKey{field: i, typ: typ}
+	unmarshal, ok := c[key]
+	if ok {
+		return unmarshal, nil
+	}
+	unmarshal, err := resolveUnmarshaler(typ, t)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	c[col] = fct
-	return nil
+	c[key] = unmarshal
+	return unmarshal, nil
 }
 
-// Use cache if present
-func (c CacheUnmarshaler) use(col int, s string, v reflect.Value) (bool, error) {
-	fctCache, ok := c[col]
+// resolveUnmarshaler chooses the unmarshaler of a type
+func resolveUnmarshaler(typ reflect.Type, t tag) (unmarshaler, error) {
+	// Use registered converter
+	if t.conv.handles(typ) {
+		return t.conv.unmarshal, nil
+	}
+
+	// Built-in types
+	unmarshal, ok := unmarshalersTypeConfig[typ]
 	if ok {
-		return true, fctCache(s, v)
+		return unmarshal, nil
+	}
+
+	// Unmarshalers with checks
+	for _, check := range unmarshalersWithCheckConfig {
+		unmarshal := check(typ, t)
+		if unmarshal != nil {
+			return unmarshal, nil
+		}
 	}
-	return false, nil
+
+	// Choose the field type
+	k := typ.Kind()
+	unmarshal, ok = unmarshalersConfig[k]
+	if !ok {
+		return nil, fmt.Errorf("unknown type %s", k)
+	}
+	return unmarshal, nil
 }
 
 // Unmarhsal a list of fields in the given instance
 func Unmarshal[T any](ct CacheTags[T], cm CacheUnmarshaler, inputs []string, item *T) error {
+	return UnmarshalContext(context.Background(), ct, cm, Row{}, inputs, item)
+}
+
+// UnmarshalContext unmarshals a list of fields in the given instance
+// The context and the row are given to lib.ContextUnmarshaler and lib.FieldUnmarshaler (and to the metadata fields)
+func UnmarshalContext[T any](
+	ctx context.Context, ct CacheTags[T], cm CacheUnmarshaler, row Row, inputs []string, item *T,
+) error {
+	return unmarshalValue(withRow(ctx, row, inputs), ct, cm, inputs, ct.structValue(item))
+}
+
+// structValue returns the struct to be filled
+// If T is a pointer to struct, the pointed struct is used (allocated if nil)
+func (ct CacheTags[T]) structValue(item *T) reflect.Value {
+	val := reflect.ValueOf(item).Elem()
+	if val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			val.Set(reflect.New(ct.typ))
+		}
+		val = val.Elem()
+	}
+	return val
+}
+
+// unmarshalValue fills the fields of the given struct value
+func unmarshalValue[T any](
+	ctx context.Context, ct CacheTags[T], cm CacheUnmarshaler, inputs []string, val reflect.Value,
+) error {
+	// Call the row hook
+	err := beforeUnmarshal(val, inputs)
+	if err != nil {
+		return err
+	}
+
+	_, err = unmarshalFields(ctx, ct, cm, inputs, val)
+	if err != nil {
+		return err
+	}
+
+	// Call the row hook (ie. cross-field validation)
+	return afterUnmarshal(val)
+}
+
+// unmarshalFields fills the fields of the given struct value (without calling the row hooks)
+// It returns the fields decoded from a non empty value
+func unmarshalFields[T any](
+	ctx context.Context, ct CacheTags[T], cm CacheUnmarshaler, inputs []string, val reflect.Value,
+) (lib.Presence, error) {
 	// Error helper using item column
 	makeErr := func(col int, e error) error {
 		return fmt.Errorf("col %d: %w", col, e)
 	}
 
-	val := reflect.Indirect(reflect.ValueOf(item))
 	typ := val.Type()
+
+	// Record the fields decoded from a non empty value
+	presence := lib.NewPresence()
+	if ct.presence >= 0 {
+		val.Field(ct.presence).Set(reflect.ValueOf(presence))
+	}
+	ct.setMetadata(ctx, val)
+
 	for i := 0; i < typ.NumField(); i++ {
 		// Get "csv" info => parse `csv:"tag0,tag1,..,tagN"`
 		tag, ok := ct.tag(i)
 		if ok {
 			col := tag.col
-			omitEmpty := tag.omitEmpty
 
-			if col >= len(inputs) {
-				if omitEmpty {
-					continue
+			// Several columns, using lib.MultiUnmarshaler
+			if len(tag.cols) > 0 {
+				ins, err := tag.columnInputs(inputs)
+				if err != nil {
+					return lib.Presence{}, fmt.Errorf("field %s: %w", typ.Field(i).Name, err)
 				}
-				return fmt.Errorf("column %d out of bounds", col)
+				field := val.Field(i)
+				if tag.isNullColumns(ins) {
+					switch tag.empty {
+					case EmptyError:
+						return lib.Presence{}, makeErr(col, fmt.Errorf("empty value"))
+					case EmptyZero:
+						field.Set(reflect.Zero(field.Type()))
+						continue
+					case EmptyMissing:
+						continue
+					}
+				} else {
+					presence.Set(typ.Field(i).Name)
+				}
+				err = unmarshalColumns(ins, field)
+				if err != nil {
+					return lib.Presence{}, makeErr(col, err)
+				}
+				continue
 			}
 
-			// Fetch data
-			input := inputs[col]
-			if input == "" && omitEmpty { // omit empty
-				continue
+			// Out of bounds columns are considered as empty (if allowed)
+			var input string
+			switch {
+			case col < len(inputs):
+				input = inputs[col]
+			case tag.empty == emptyUnset || tag.empty == EmptyError:
+				return lib.Presence{}, fmt.Errorf("column %d out of bounds", col)
 			}
 
 			// Fetch current attribute
 			field := val.Field(i)
 
-			// If pointer, allocate a new object and use it
-			if field.Type().Kind() == reflect.Ptr {
-				field.Set(reflect.New(field.Type().Elem()))
-				field = field.Elem()
-			}
-
-			// Use cache if filled
-			ok, err := cm.use(col, input, field)
-			switch {
-			case err != nil:
-				return makeErr(col, err)
-			case ok:
-				continue
+			// Read the part of the column (if defined)
+			if tag.part != "" {
+				var err error
+				input, err = ct.cellPart(tag, input)
+				if err != nil {
+					return lib.Presence{}, makeErr(col, err)
+				}
 			}
 
-			// Unmarshalers with checks
-			ok, err = cm.storeWithChecks(col, input, field, unmarshalersWithCheckConfig)
-			switch {
-			case err != nil:
-				return makeErr(col, err)
-			case ok:
-				continue
+			// Normalize the input (trim, case...), null values are considered as empty
+			input, isNull := tag.normalizeInput(input)
+			if isNull {
+				switch tag.empty {
+				case EmptyError:
+					return lib.Presence{}, makeErr(col, fmt.Errorf("empty value"))
+				case EmptyZero:
+					field.Set(reflect.Zero(field.Type()))
+					continue
+				case EmptyMissing:
+					continue
+				case EmptyDefault:
+					input = tag.defaultVal
+				default:
+					input = ""
+				}
+			} else {
+				presence.Set(typ.Field(i).Name)
 			}
 
-			// Choose the field type
-			k := field.Type().Kind()
-			unmarshal, ok := unmarshalersConfig[k]
-			if !ok {
-				return makeErr(col, fmt.Errorf("unknown type %s", k))
-			}
-			err = cm.store(col, input, field, unmarshal)
+			// Unmarshal the field
+			err := cm.unmarshalField(ctx, i, input, field, tag)
 			if err != nil {
-				return makeErr(col, err)
+				return lib.Presence{}, makeErr(col, err)
 			}
 		}
 	}
+
+	return presence, nil
+}
+
+// unmarshalField chooses the unmarshaler of the ith field (using the cache if filled)
+func (c CacheUnmarshaler) unmarshalField(ctx context.Context, i int, input string, field reflect.Value, t tag) error {
+	// If interface, allocate a new object using the factory (unless converted as is)
+	if field.Type().Kind() == reflect.Interface && !t.conv.handles(field.Type()) {
+		return c.unmarshalInterface(ctx, i, input, field, t)
+	}
+
+	// If pointer, allocate a new object and use it (unless converted as is)
+	if field.Type().Kind() == reflect.Ptr && !t.conv.handles(field.Type()) {
+		field.Set(reflect.New(field.Type().Elem()))
+		return c.unmarshalField(ctx, i, input, field.Elem(), t)
+	}
+
+	unmarshal, err := c.unmarshaler(i, field.Type(), t)
+	if err != nil {
+		return err
+	}
+	return unmarshal(ctx, input, field, t)
+}
+
+// unmarshalInterface uses the factory to choose the dynamic type of the field
+func (c CacheUnmarshaler) unmarshalInterface(
+	ctx context.Context, i int, input string, field reflect.Value, t tag,
+) error {
+	if t.factory == nil {
+		return fmt.Errorf("no factory defined for %s", field.Type())
+	}
+	value, err := t.factory(input)
+	if err != nil {
+		return err
+	}
+
+	// A pointer is filled in place, a value is filled using a copy
+	if value.Kind() == reflect.Ptr {
+		err = c.unmarshalField(ctx, i, input, value.Elem(), t)
+	} else {
+		target := reflect.New(value.Type()).Elem()
+		target.Set(value)
+		err = c.unmarshalField(ctx, i, input, target, t)
+		value = target
+	}
+	if err != nil {
+		return err
+	}
+	field.Set(value)
 	return nil
 }
 
+var unmarshalersTypeConfig = map[reflect.Type]unmarshaler{
+	reflect.TypeOf(time.Time{}):      timeUnmarshaler,
+	reflect.TypeOf(time.Duration(0)): durationUnmarshaler,
+	reflect.TypeOf(time.Month(0)):    monthUnmarshaler,
+	reflect.TypeOf(time.Weekday(0)):  weekdayUnmarshaler,
+	reflect.TypeOf(netip.Addr{}):     addrUnmarshaler,
+	reflect.TypeOf(netip.Prefix{}):   prefixUnmarshaler,
+	reflect.TypeOf(url.URL{}):        urlUnmarshaler,
+	reflect.TypeOf(big.Int{}):        bigIntUnmarshaler,
+	reflect.TypeOf(big.Float{}):      bigFloatUnmarshaler,
+	reflect.TypeOf(big.Rat{}):        bigRatUnmarshaler,
+}
+
 var unmarshalersWithCheckConfig = []unmarshalerWithCheck{
+	jsonUnmarshaler,
+	binaryUnmarshaler,
+	fieldUnmarshaler,
+	contextUnmarshaler,
 	csvUnmarshaler,
 	textUnmarshaler,
 }
@@ -168,6 +338,8 @@ var unmarshalersConfig = map[reflect.Kind]unmarshaler{
 	reflect.Uint32: uintUnmarshaler,
 	reflect.Uint64: uintUnmarshaler,
 
+	reflect.Uintptr: uintUnmarshaler,
+
 	reflect.Float32: floatUnmarshaler,
 	reflect.Float64: floatUnmarshaler,
 
@@ -176,27 +348,64 @@ var unmarshalersConfig = map[reflect.Kind]unmarshaler{
 	reflect.Bool: boolUnmarshaler,
 }
 
+// Check for json unmarshaler (if enabled by the tag)
+func jsonUnmarshaler(typ reflect.Type, t tag) unmarshaler {
+	if !t.json || !reflect.PointerTo(typ).Implements(jsonUnmarshalerType) {
+		return nil
+	}
+	return func(_ context.Context, in string, field reflect.Value, _ tag) error {
+		return field.Addr().Interface().(json.Unmarshaler).UnmarshalJSON([]byte(in))
+	}
+}
+
+// Check for binary unmarshaler (if enabled by the tag)
+func binaryUnmarshaler(typ reflect.Type, t tag) unmarshaler {
+	if !t.binary || !reflect.PointerTo(typ).Implements(binaryUnmarshalerType) {
+		return nil
+	}
+	return func(_ context.Context, in string, field reflect.Value, _ tag) error {
+		return field.Addr().Interface().(encoding.BinaryUnmarshaler).UnmarshalBinary([]byte(in))
+	}
+}
+
+// Check for csv unmarshaler using a context
+func contextUnmarshaler(typ reflect.Type, _ tag) unmarshaler {
+	if !reflect.PointerTo(typ).Implements(contextUnmarshalerType) {
+		return nil
+	}
+	return func(ctx context.Context, in string, field reflect.Value, _ tag) error {
+		return field.Addr().Interface().(lib.ContextUnmarshaler).UnmarshalCSVContext(ctx, in)
+	}
+}
+
 // Check for csv unmarshaler
-func csvUnmarshaler(in string, field reflect.Value) (bool, error) {
-	u, ok := field.Addr().Interface().(lib.Unmarshaler)
-	if !ok {
-		return false, nil
+func csvUnmarshaler(typ reflect.Type, _ tag) unmarshaler {
+	if !reflect.PointerTo(typ).Implements(csvUnmarshalerType) {
+		return nil
+	}
+	return func(_ context.Context, in string, field reflect.Value, _ tag) error {
+		return field.Addr().Interface().(lib.Unmarshaler).UnmarshalCSV(in)
 	}
-	return true, u.UnmarshalCSV(in)
 }
 
 // Check for text unmarshaler
-func textUnmarshaler(in string, field reflect.Value) (bool, error) {
-	u, ok := field.Addr().Interface().(encoding.TextUnmarshaler)
-	if !ok {
-		return false, nil
+func textUnmarshaler(typ reflect.Type, _ tag) unmarshaler {
+	if !reflect.PointerTo(typ).Implements(textUnmarshalerType) {
+		return nil
+	}
+	return func(_ context.Context, in string, field reflect.Value, _ tag) error {
+		return field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(in))
 	}
-
-	return true, u.UnmarshalText([]byte(in))
 }
 
-func intUnmarshaler(in string, field reflect.Value) error {
-	res, err := strconv.ParseInt(in, 0, 64)
+// Parse an integer using the field size (an out of range value returns an error) and the tag base
+func intUnmarshaler(_ context.Context, in string, field reflect.Value, t tag) error {
+	bits := field.Type().Bits()
+	res, err := strconv.ParseInt(t.trimPrefix(in), t.base, bits)
+	if errors.Is(err, strconv.ErrRange) {
+		minInt, maxInt := int64(-1)<<(bits-1), int64(1)<<(bits-1)-1
+		return fmt.Errorf("value %q overflows %s [%d, %d]", in, field.Type(), minInt, maxInt)
+	}
 	if err != nil {
 		return err
 	}
@@ -204,8 +413,14 @@ func intUnmarshaler(in string, field reflect.Value) error {
 	return nil
 }
 
-func uintUnmarshaler(in string, field reflect.Value) error {
-	res, err := strconv.ParseUint(in, 0, 64)
+// Parse an unsigned integer using the field size (an out of range value returns an error) and the tag base
+func uintUnmarshaler(_ context.Context, in string, field reflect.Value, t tag) error {
+	bits := field.Type().Bits()
+	res, err := strconv.ParseUint(t.trimPrefix(in), t.base, bits)
+	if errors.Is(err, strconv.ErrRange) || (err != nil && isNegative(in, t)) {
+		maxUint := uint64(1)<<(bits-1)<<1 - 1
+		return fmt.Errorf("value %q overflows %s [0, %d]", in, field.Type(), maxUint)
+	}
 	if err != nil {
 		return err
 	}
@@ -213,7 +428,13 @@ func uintUnmarshaler(in string, field reflect.Value) error {
 	return nil
 }
 
-func floatUnmarshaler(in string, field reflect.Value) error {
+// isNegative reports whether the input is a negative integer
+func isNegative(in string, t tag) bool {
+	_, err := strconv.ParseInt(t.trimPrefix(in), t.base, 64)
+	return strings.HasPrefix(in, "-") && (err == nil || errors.Is(err, strconv.ErrRange))
+}
+
+func floatUnmarshaler(_ context.Context, in string, field reflect.Value, _ tag) error {
 	res, err := strconv.ParseFloat(in, 64)
 	if err != nil {
 		return err
@@ -222,12 +443,156 @@ func floatUnmarshaler(in string, field reflect.Value) error {
 	return nil
 }
 
-func stringUnmarshaler(in string, field reflect.Value) error {
+func stringUnmarshaler(_ context.Context, in string, field reflect.Value, _ tag) error {
 	field.SetString(in)
 	return nil
 }
 
-func boolUnmarshaler(in string, field reflect.Value) error {
-	field.SetBool(in == "true")
+func boolUnmarshaler(_ context.Context, in string, field reflect.Value, t tag) error {
+	switch {
+	case containsFold(t.trueValues, in):
+		field.SetBool(true)
+	case containsFold(t.falseValues, in):
+		field.SetBool(false)
+	default:
+		return fmt.Errorf("invalid boolean %q", in)
+	}
+	return nil
+}
+
+// containsFold reports whether s is within values (case insensitive)
+func containsFold(values []string, s string) bool {
+	return slices.ContainsFunc(values, func(value string) bool {
+		return strings.EqualFold(value, s)
+	})
+}
+
+func timeUnmarshaler(_ context.Context, in string, field reflect.Value, t tag) error {
+	res, err := time.Parse(t.layout, in)
+	if err != nil {
+		return err
+	}
+	field.Set(reflect.ValueOf(res))
+	return nil
+}
+
+// Parse a duration using the go syntax ("1h2m3s") or a number of seconds ("3723")
+func durationUnmarshaler(_ context.Context, in string, field reflect.Value, _ tag) error {
+	seconds, err := strconv.ParseFloat(in, 64)
+	if err == nil {
+		ns := seconds * float64(time.Second)
+		if math.IsNaN(ns) || ns < math.MinInt64 || ns >= math.MaxInt64 {
+			return fmt.Errorf("duration %q out of range", in)
+		}
+		field.SetInt(int64(ns))
+		return nil
+	}
+	res, err := time.ParseDuration(in)
+	if err != nil {
+		return err
+	}
+	field.SetInt(int64(res))
+	return nil
+}
+
+// Parse a month using its number ("1") or its name ("January", "Jan")
+func monthUnmarshaler(_ context.Context, in string, field reflect.Value, _ tag) error {
+	for m := time.January; m <= time.December; m++ {
+		if strings.EqualFold(in, m.String()) || strings.EqualFold(in, m.String()[:3]) {
+			field.SetInt(int64(m))
+			return nil
+		}
+	}
+	res, err := strconv.Atoi(in)
+	if err != nil || res < int(time.January) || res > int(time.December) {
+		return fmt.Errorf("invalid month %q", in)
+	}
+	field.SetInt(int64(res))
+	return nil
+}
+
+// Parse a weekday using its number ("0" for sunday) or its name ("Sunday", "Sun")
+func weekdayUnmarshaler(_ context.Context, in string, field reflect.Value, _ tag) error {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(in, d.String()) || strings.EqualFold(in, d.String()[:3]) {
+			field.SetInt(int64(d))
+			return nil
+		}
+	}
+	res, err := strconv.Atoi(in)
+	if err != nil || res < int(time.Sunday) || res > int(time.Saturday) {
+		return fmt.Errorf("invalid weekday %q", in)
+	}
+	field.SetInt(int64(res))
+	return nil
+}
+
+func addrUnmarshaler(_ context.Context, in string, field reflect.Value, _ tag) error {
+	res, err := netip.ParseAddr(in)
+	if err != nil {
+		return err
+	}
+	field.Set(reflect.ValueOf(res))
+	return nil
+}
+
+func prefixUnmarshaler(_ context.Context, in string, field reflect.Value, _ tag) error {
+	res, err := netip.ParsePrefix(in)
+	if err != nil {
+		return err
+	}
+	field.Set(reflect.ValueOf(res))
+	return nil
+}
+
+func urlUnmarshaler(_ context.Context, in string, field reflect.Value, _ tag) error {
+	res, err := url.Parse(in)
+	if err != nil {
+		return err
+	}
+	field.Set(reflect.ValueOf(*res))
+	return nil
+}
+
+func bigIntUnmarshaler(_ context.Context, in string, field reflect.Value, _ tag) error {
+	_, ok := field.Addr().Interface().(*big.Int).SetString(in, 10)
+	if !ok {
+		return fmt.Errorf("invalid big integer %q", in)
+	}
+	return nil
+}
+
+// Parse a big float using the tag precision and rounding mode
+func bigFloatUnmarshaler(_ context.Context, in string, field reflect.Value, t tag) error {
+	prec := t.prec
+	if prec == 0 {
+		prec = digitsPrec(in)
+	}
+	res, _, err := big.ParseFloat(in, 10, prec, t.mode)
+	if err != nil {
+		return err
+	}
+	field.Set(reflect.ValueOf(*res))
+	return nil
+}
+
+// digitsPrec returns the precision (in bits) keeping every digit of the decimal mantissa (at least 64)
+func digitsPrec(in string) uint {
+	mantissa, _, _ := strings.Cut(strings.ToLower(in), "e")
+	digits := 0
+	for _, r := range mantissa {
+		if r >= '0' && r <= '9' {
+			digits++
+		}
+	}
+	return max(64, uint(math.Ceil(float64(digits)*math.Log2(10)))+1)
+}
+
+// Parse a big rational using a decimal ("1.25") or a fraction ("5/4")
+func bigRatUnmarshaler(_ context.Context, in string, field reflect.Value, _ tag) error {
+	_, ok := field.Addr().Interface().(*big.Rat).SetString(in)
+	if !ok {
+		return fmt.Errorf("invalid big rational %q", in)
+	}
 	return nil
 }
@@ -1,6 +1,10 @@
 package internal
 
 import (
+	"math/big"
+	"net/netip"
+	"net/url"
+	"slices"
 	"testing"
 	"time"
 
@@ -17,6 +21,26 @@ func (it *customUnmarshal) UnmarshalCSV(s string) error {
 	return nil
 }
 
+// multiUnmarshal implements the json and binary unmarshalers
+type multiUnmarshal struct {
+	value string
+}
+
+func (it *multiUnmarshal) UnmarshalJSON(b []byte) error {
+	it.value = "json:" + string(b)
+	return nil
+}
+
+func (it *multiUnmarshal) UnmarshalBinary(b []byte) error {
+	it.value = "bin:" + string(b)
+	return nil
+}
+
+func (it *multiUnmarshal) UnmarshalText(b []byte) error {
+	it.value = "text:" + string(b)
+	return nil
+}
+
 func testUnmarshal[T any](ct CacheTags[T], cm CacheUnmarshaler, inputs []string) T {
 	var ts T
 	err := Unmarshal(ct, cm, inputs, &ts)
@@ -54,6 +78,126 @@ func TestUnmarshalCSV(t *testing.T) {
 		})
 	})
 
+	Convey("boolean values", t, func() {
+		type testStruct struct {
+			Bool1 bool `csv:"0"`
+			Bool2 bool `csv:"1"`
+			Bool3 bool `csv:"2,bool=oui|non"`
+			Bool4 bool `csv:"3,true=yes|y,false=no|n"`
+		}
+
+		ct, err := NewCacheTags[testStruct]()
+		So(err, ShouldBeNil)
+		cm := NewCacheUnmarshaler()
+		ts := testUnmarshal(ct, cm, []string{
+			"TRUE",
+			"0",
+			"Oui",
+			"N",
+		})
+		So(ts, ShouldResemble, testStruct{
+			Bool1: true,
+			Bool2: false,
+			Bool3: true,
+			Bool4: false,
+		})
+
+		Convey("when invalid value", func() {
+			var ts testStruct
+			err := Unmarshal(ct, cm, []string{"yes", "false", "non", "y"}, &ts)
+			So(err, ShouldBeError, `col 0: invalid boolean "yes"`)
+		})
+	})
+
+	Convey("null values", t, func() {
+		type testStruct struct {
+			Ptr1   *int    `csv:"0,omitempty"`
+			Ptr2   *int    `csv:"1,omitempty,null=NA|-"`
+			Int    int     `csv:"2,omitempty"`
+			Str    string  `csv:"3"`
+			StrPtr *string `csv:"4,omitempty"`
+		}
+
+		ct, err := NewCacheTags[testStruct](WithNullValues(`\N`, "NULL"))
+		So(err, ShouldBeNil)
+		cm := NewCacheUnmarshaler()
+		ts := testUnmarshal(ct, cm, []string{
+			"NULL",
+			"-",
+			`\N`,
+			"NULL",
+			"",
+		})
+		So(ts, ShouldResemble, testStruct{
+			Ptr1:   nil,
+			Ptr2:   nil,
+			Int:    0,
+			Str:    "",
+			StrPtr: nil,
+		})
+
+		Convey("when not a null value", func() {
+			ts := testUnmarshal(ct, cm, []string{"1", "NA", "3", "NA", "-"})
+			So(ts.Ptr1, ShouldNotBeNil)
+			So(*ts.Ptr1, ShouldEqual, 1)
+			So(ts.Int, ShouldEqual, 3)
+			So(ts.Str, ShouldEqual, "NA")
+			So(ts.StrPtr, ShouldNotBeNil)
+			So(*ts.StrPtr, ShouldEqual, "-")
+			So(ts.Ptr2, ShouldBeNil)
+		})
+
+		Convey("when the null value is overridden", func() {
+			var ts testStruct
+			err := Unmarshal(ct, cm, []string{"", "NULL", "", "", ""}, &ts)
+			So(err, ShouldBeError, `col 1: strconv.ParseInt: parsing "NULL": invalid syntax`)
+		})
+	})
+
+	Convey("empty policy", t, func() {
+		type testStruct struct {
+			Zero    int     `csv:"0,empty=zero"`
+			Default int     `csv:"1,default=42"`
+			Missing int     `csv:"2,empty=missing"`
+			Ptr     *int    `csv:"3,empty=zero"`
+			Str     string  `csv:"4,empty=error"`
+			Out     float64 `csv:"5,default=1.5"`
+			Set     lib.Presence
+		}
+
+		ct, err := NewCacheTags[testStruct]()
+		So(err, ShouldBeNil)
+		cm := NewCacheUnmarshaler()
+		ts := testStruct{Zero: 1, Missing: 2, Ptr: new(int)}
+		err = Unmarshal(ct, cm, []string{"", "", "", "", "str"}, &ts)
+		So(err, ShouldBeNil)
+		So(ts.Zero, ShouldEqual, 0)
+		So(ts.Default, ShouldEqual, 42)
+		So(ts.Missing, ShouldEqual, 2)
+		So(ts.Ptr, ShouldBeNil)
+		So(ts.Str, ShouldEqual, "str")
+		So(ts.Out, ShouldEqual, 1.5)
+		So(ts.Set.IsSet("Zero"), ShouldBeFalse)
+		So(ts.Set.IsSet("Default"), ShouldBeFalse)
+		So(ts.Set.IsSet("Str"), ShouldBeTrue)
+
+		Convey("when explicit zero", func() {
+			ts := testUnmarshal(ct, cm, []string{"0", "0", "0", "0", "str", "0"})
+			So(ts.Default, ShouldEqual, 0)
+			So(ts.Set.IsSet("Zero"), ShouldBeTrue)
+			So(ts.Set.IsSet("Default"), ShouldBeTrue)
+			So(ts.Set.IsSet("Missing"), ShouldBeTrue)
+			So(ts.Set.IsSet("Ptr"), ShouldBeTrue)
+			So(ts.Set.IsSet("Out"), ShouldBeTrue)
+		})
+
+		Convey("when empty value is an error", func() {
+			var ts testStruct
+			err := Unmarshal(ct, cm, []string{"", "", "", "", ""}, &ts)
+			So(err, ShouldBeError, "col 4: empty value")
+		})
+	})
+
 	Convey("string", t, func() {
 		type testStruct struct {
 			Str1     string  `csv:"0"`
@@ -90,7 +234,7 @@ func TestUnmarshalCSV(t *testing.T) {
 			Int16 int16 `csv:"2"`
 			Int32 int32 `csv:"3"`
 			Int64 int64 `csv:"4"`
-			Ptr   *int  `csv:"5"`
+			Ptr   *int  `csv:"5,base=0"`
 		}
 
 		ct, err := NewCacheTags[testStruct]()
@@ -115,6 +259,112 @@ func TestUnmarshalCSV(t *testing.T) {
 		})
 	})
 
+	Convey("integer base", t, func() {
+		type testStruct struct {
+			Dec    int    `csv:"0"`
+			Auto   int    `csv:"1,base=0"`
+			Bin    uint8  `csv:"2,base=2"`
+			Oct    int    `csv:"3,base=8,prefix"`
+			Hex    int64  `csv:"4,base=16,prefix"`
+			HexRaw uint16 `csv:"5,base=16"`
+			Padded int    `csv:"6,pad=5"`
+		}
+
+		ct, err := NewCacheTags[testStruct]()
+		So(err, ShouldBeNil)
+		cm := NewCacheUnmarshaler()
+		ts := testUnmarshal(ct, cm, []string{
+			"0755",
+			"0x1F",
+			"101",
+			"0o17",
+			"-0X1f",
+			"ff",
+			"00042",
+		})
+		So(ts, ShouldResemble, testStruct{
+			Dec:    755,
+			Auto:   31,
+			Bin:    5,
+			Oct:    15,
+			Hex:    -31,
+			HexRaw: 255,
+			Padded: 42,
+		})
+
+		Convey("when ko", func() {
+			var ts testStruct
+			err := Unmarshal(ct, cm, []string{"1_000", "0", "0", "0", "0", "0", "0"}, &ts)
+			So(err, ShouldBeError, `col 0: strconv.ParseInt: parsing "1_000": invalid syntax`)
+
+			err = Unmarshal(ct, cm, []string{"0x1F", "0", "0", "0", "0", "0", "0"}, &ts)
+			So(err, ShouldBeError, `col 0: strconv.ParseInt: parsing "0x1F": invalid syntax`)
+
+			err = Unmarshal(ct, cm, []string{"0", "0", "2", "0", "0", "0", "0"}, &ts)
+			So(err, ShouldBeError, `col 2: strconv.ParseUint: parsing "2": invalid syntax`)
+		})
+	})
+
+	Convey("integer overflow", t, func() {
+		type level int8
+		type testStruct struct {
+			Int8   int8    `csv:"0,omitempty"`
+			Int16  int16   `csv:"1,omitempty"`
+			Int32  *int32  `csv:"2,omitempty"`
+			Int64  int64   `csv:"3,omitempty"`
+			Uint8  uint8   `csv:"4,omitempty"`
+			Uint   uint    `csv:"5,omitempty"`
+			Uint64 uint64  `csv:"6,omitempty"`
+			Ptr    uintptr `csv:"7,omitempty"`
+			Level  level   `csv:"8,omitempty"`
+		}
+
+		ct, err := NewCacheTags[testStruct]()
+		So(err, ShouldBeNil)
+		cm := NewCacheUnmarshaler()
+		ts := testUnmarshal(ct, cm, []string{
+			"-128", "32767", "-2147483648", "9223372036854775807", "255", "0", "18446744073709551615", "42", "127",
+		})
+		So(ts.Int8, ShouldEqual, -128)
+		So(*ts.Int32, ShouldEqual, -2147483648)
+		So(ts.Uint8, ShouldEqual, 255)
+		So(ts.Uint64, ShouldEqual, uint64(18446744073709551615))
+		So(ts.Ptr, ShouldEqual, uintptr(42))
+		So(ts.Level, ShouldEqual, level(127))
+
+		Convey("when ko", func() {
+			for col, input := range map[int]string{
+				0: "300",
+				1: "32768",
+				2: "-2147483649",
+				3: "9223372036854775808",
+				4: "256",
+				5: "-1",
+				6: "18446744073709551616",
+				8: "-129",
+			} {
+				inputs := make([]string, 9)
+				inputs[col] = input
+				var ts testStruct
+				err := Unmarshal(ct, cm, inputs, &ts)
+				So(err, ShouldBeError, map[int]string{
+					0: `col 0: value "300" overflows int8 [-128, 127]`,
+					1: `col 1: value "32768" overflows int16 [-32768, 32767]`,
+					2: `col 2: value "-2147483649" overflows int32 [-2147483648, 2147483647]`,
+					3: `col 3: value "9223372036854775808" overflows int64 [-9223372036854775808, 9223372036854775807]`,
+					4: `col 4: value "256" overflows uint8 [0, 255]`,
+					5: `col 5: value "-1" overflows uint [0, 18446744073709551615]`,
+					6: `col 6: value "18446744073709551616" overflows uint64 [0, 18446744073709551615]`,
+					8: `col 8: value "-129" overflows internal.level [-128, 127]`,
+				}[col])
+			}
+
+			var ts testStruct
+			err := Unmarshal(ct, cm, []string{"", "", "", "", "", "-x"}, &ts)
+			So(err, ShouldBeError, `col 5: strconv.ParseUint: parsing "-x": invalid syntax`)
+		})
+	})
+
 	Convey("float", t, func() {
 		type testStruct struct {
 			Flt32  float32  `csv:"0"`
@@ -177,6 +427,165 @@ func TestUnmarshalCSV(t *testing.T) {
 		})
 	})
 
+	Convey("built-in types", t, func() {
+		type testStruct struct {
+			Time     time.Time      `csv:"0"`
+			Date     time.Time      `csv:"1,layout=2006-01-02"`
+			Dur1     time.Duration  `csv:"2"`
+			Dur2     time.Duration  `csv:"3"`
+			Month1   time.Month     `csv:"4"`
+			Month2   time.Month     `csv:"5"`
+			Weekday1 time.Weekday   `csv:"6"`
+			Weekday2 time.Weekday   `csv:"7"`
+			Addr     netip.Addr     `csv:"8"`
+			Prefix   netip.Prefix   `csv:"9"`
+			URL      *url.URL       `csv:"10"`
+			DurPtr   *time.Duration `csv:"11,omitempty"`
+		}
+
+		ct, err := NewCacheTags[testStruct]()
+		So(err, ShouldBeNil)
+		cm := NewCacheUnmarshaler()
+		ts := testUnmarshal(ct, cm, []string{
+			"2023-02-03T10:11:12.5Z",
+			"2023-02-03",
+			"1h2m3s",
+			"1.5",
+			"3",
+			"dec",
+			"0",
+			"Saturday",
+			"192.168.0.1",
+			"10.0.0.0/8",
+			"https://example.com/path?q=1",
+			"",
+		})
+		So(ts, ShouldResemble, testStruct{
+			Time:     time.Date(2023, 2, 3, 10, 11, 12, 500000000, time.UTC),
+			Date:     time.Date(2023, 2, 3, 0, 0, 0, 0, time.UTC),
+			Dur1:     time.Hour + 2*time.Minute + 3*time.Second,
+			Dur2:     1500 * time.Millisecond,
+			Month1:   time.March,
+			Month2:   time.December,
+			Weekday1: time.Sunday,
+			Weekday2: time.Saturday,
+			Addr:     netip.MustParseAddr("192.168.0.1"),
+			Prefix:   netip.MustParsePrefix("10.0.0.0/8"),
+			URL:      &url.URL{Scheme: "https", Host: "example.com", Path: "/path", RawQuery: "q=1"},
+			DurPtr:   nil,
+		})
+
+		Convey("when ko", func() {
+			inputs := []string{
+				"2023-02-03T10:11:12Z", "2023-02-03", "1h", "1", "1", "1", "1", "1", "::1", "::/0", "/", "",
+			}
+			for idx, input := range map[int]string{
+				0: "2023-02-03",
+				2: "oups",
+				4: "13",
+				5: "juin",
+				6: "7",
+				8: "256.0.0.1",
+				9: "10.0.0.0",
+			} {
+				values := slices.Clone(inputs)
+				values[idx] = input
+				var ts testStruct
+				err := Unmarshal(ct, NewCacheUnmarshaler(), values, &ts)
+				So(err, ShouldNotBeNil)
+			}
+
+			for _, input := range []string{"NaN", "Inf", "-Inf", "1e20", "-1e20"} {
+				values := slices.Clone(inputs)
+				values[2] = input
+				var ts testStruct
+				err := Unmarshal(ct, NewCacheUnmarshaler(), values, &ts)
+				So(err, ShouldBeError, `col 2: duration "`+input+`" out of range`)
+			}
+		})
+	})
+
+	Convey("big float without precision", t, func() {
+		type testStruct struct {
+			Amount big.Float `csv:"0"`
+			Small  big.Float `csv:"1"`
+		}
+
+		ct, err := NewCacheTags[testStruct]()
+		So(err, ShouldBeNil)
+		in := []string{"123456789012345678901234567890.12", "1.5e-3"}
+		ts := testUnmarshal(ct, NewCacheUnmarshaler(), in)
+		So(ts.Amount.Prec(), ShouldBeGreaterThan, 64)
+		So(ts.Small.Prec(), ShouldEqual, 64)
+		So(testMarshal(ct, NewCacheMarshaler(), ts), ShouldResemble, []string{"123456789012345678901234567890.12", "0.0015"})
+	})
+
+	Convey("big numbers", t, func() {
+		type testStruct struct {
+			Int      big.Int    `csv:"0"`
+			IntPtr   *big.Int   `csv:"1"`
+			Float    big.Float  `csv:"2,prec=128"`
+			FloatPtr *big.Float `csv:"3,prec=8,mode=ToZero"`
+			Rat1     big.Rat    `csv:"4"`
+			Rat2     *big.Rat   `csv:"5"`
+			IntNil   *big.Int   `csv:"6,omitempty"`
+		}
+
+		ct, err := NewCacheTags[testStruct]()
+		So(err, ShouldBeNil)
+		cm := NewCacheUnmarshaler()
+		ts := testUnmarshal(ct, cm, []string{
+			"123456789012345678901234567890",
+			"-98765432109876543210",
+			"12345678901234567890.123456789",
+			"255.9",
+			"1234.56",
+			"5/4",
+			"",
+		})
+		So(ts.Int.String(), ShouldEqual, "123456789012345678901234567890")
+		So(ts.IntPtr.String(), ShouldEqual, "-98765432109876543210")
+		So(ts.Float.Prec(), ShouldEqual, 128)
+		So(ts.Float.Text('f', 9), ShouldEqual, "12345678901234567890.123456789")
+		So(ts.FloatPtr.Prec(), ShouldEqual, 8)
+		So(ts.FloatPtr.Text('f', -1), ShouldEqual, "255")
+		So(ts.Rat1.RatString(), ShouldEqual, "30864/25")
+		So(ts.Rat2.RatString(), ShouldEqual, "5/4")
+		So(ts.IntNil, ShouldBeNil)
+
+		Convey("when ko", func() {
+			var ts testStruct
+			err := Unmarshal(ct, cm, []string{"0x10", "1", "1", "1", "1", "1", ""}, &ts)
+			So(err, ShouldBeError, `col 0: invalid big integer "0x10"`)
+
+			err = Unmarshal(ct, cm, []string{"1", "1", "1", "1", "1/0", "1", ""}, &ts)
+			So(err, ShouldBeError, `col 4: invalid big rational "1/0"`)
+
+			err = Unmarshal(ct, cm, []string{"1", "1", "one", "1", "1", "1", ""}, &ts)
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("decimals", t, func() {
+		type testStruct struct {
+			Dec   lib.Decimal  `csv:"0"`
+			Ptr   *lib.Decimal `csv:"1"`
+			Money lib.Money    `csv:"2"`
+		}
+
+		ct, err := NewCacheTags[testStruct]()
+		So(err, ShouldBeNil)
+		cm := NewCacheUnmarshaler()
+		ts := testUnmarshal(ct, cm, []string{
+			"1234.56",
+			"-0.10",
+			"12.50 EUR",
+		})
+		So(ts.Dec.String(), ShouldEqual, "1234.56")
+		So(ts.Ptr.String(), ShouldEqual, "-0.10")
+		So(ts.Money.String(), ShouldEqual, "12.50 EUR")
+	})
+
 	Convey("custom struct", t, func() {
 		type testStruct struct {
 			Custom1   customUnmarshal  `csv:"0"`
@@ -225,4 +634,130 @@ func TestUnmarshalCSV(t *testing.T) {
 			TimeNil:   nil,
 		})
 	})
+
+	Convey("method sets", t, func() {
+		type testStruct struct {
+			JSON   multiUnmarshal  `csv:"0,json"`
+			Binary multiUnmarshal  `csv:"1,binary"`
+			Text   *multiUnmarshal `csv:"2"`
+		}
+
+		ct, err := NewCacheTags[testStruct]()
+		So(err, ShouldBeNil)
+		cm := NewCacheUnmarshaler()
+		So(PrepareUnmarshaler(ct, cm), ShouldBeNil)
+		ts := testUnmarshal(ct, cm, []string{`"a"`, "b", "c"})
+		So(ts, ShouldResemble, testStruct{
+			JSON:   multiUnmarshal{value: `json:"a"`},
+			Binary: multiUnmarshal{value: "bin:b"},
+			Text:   &multiUnmarshal{value: "text:c"},
+		})
+	})
+
+	Convey("unknown type", t, func() {
+		type testStruct struct {
+			Value onlyStringer `csv:"0"` // encode only
+		}
+
+		ct, err := NewCacheTags[testStruct]()
+		So(err, ShouldBeNil)
+		err = PrepareUnmarshaler(ct, NewCacheUnmarshaler())
+		So(err, ShouldBeError, "field Value: unknown type struct")
+	})
+
+	Convey("shared column", t, func() {
+		type testStruct struct {
+			Raw  string   `csv:"0"`
+			Date lib.Date `csv:"0"`
+			Ptr  *int     `csv:"1,omitempty"`
+			Num  int      `csv:"1,default=7"`
+		}
+
+		ct, err := NewCacheTags[testStruct]()
+		So(err, ShouldBeNil)
+		cm := NewCacheUnmarshaler()
+		So(PrepareUnmarshaler(ct, cm), ShouldBeNil)
+		ts := testUnmarshal(ct, cm, []string{"2023-02-03", ""})
+		So(ts, ShouldResemble, testStruct{
+			Raw:  "2023-02-03",
+			Date: lib.Date(time.Date(2023, 2, 3, 0, 0, 0, 0, time.UTC)),
+			Ptr:  nil,
+			Num:  7,
+		})
+	})
+
+	Convey("normalization", t, func() {
+		type testStruct struct {
+			City  string  `csv:"0,trim"`
+			Code  string  `csv:"1,ltrim,upper"`
+			Label string  `csv:"2,rtrim,collapse,lower"`
+			Name  string  `csv:"3,nfc"`
+			Lon   float64 `csv:"4"`
+			Opt   *int    `csv:"5,omitempty"`
+		}
+
+		ct, err := NewCacheTags[testStruct](WithTrimSpace())
+		So(err, ShouldBeNil)
+		cm := NewCacheUnmarshaler()
+		ts := testUnmarshal(ct, cm, []string{
+			" Saint-Sébastien-sur-Loire ",
+			"  fr ",
+			" Big \t  Label  ",
+			"Se\u0301bastien",
+			" -1.516",
+			"  ",
+		})
+		So(ts, ShouldResemble, testStruct{
+			City:  "Saint-Sébastien-sur-Loire",
+			Code:  "FR",
+			Label: "big label",
+			Name:  "S\u00e9bastien",
+			Lon:   -1.516,
+			Opt:   nil,
+		})
+	})
+
+	Convey("normalization and null values", t, func() {
+		type testStruct struct {
+			Code *string `csv:"0,lower,omitempty,null=NULL"`
+			Num  *int    `csv:"1,trim,omitempty,null=-"`
+		}
+
+		ct, err := NewCacheTags[testStruct]()
+		So(err, ShouldBeNil)
+		cm := NewCacheUnmarshaler()
+		So(testUnmarshal(ct, cm, []string{"NULL", " - "}), ShouldResemble, testStruct{})
+
+		ts := testUnmarshal(ct, cm, []string{"FR", " 4 "})
+		So(*ts.Code, ShouldEqual, "fr")
+		So(*ts.Num, ShouldEqual, 4)
+	})
+
+	Convey("pointers", t, func() {
+		type testStruct struct {
+			PP  **int      `csv:"0"`
+			PPS **lib.Date `csv:"1,omitempty"`
+		}
+
+		ct, err := NewCacheTags[*testStruct]()
+		So(err, ShouldBeNil)
+		cm := NewCacheUnmarshaler()
+		So(PrepareUnmarshaler(ct, cm), ShouldBeNil)
+
+		Convey("when nil element", func() {
+			var ts *testStruct
+			So(Unmarshal(ct, cm, []string{"42", "2023-02-03"}, &ts), ShouldBeNil)
+			So(**ts.PP, ShouldEqual, 42)
+			So(**ts.PPS, ShouldEqual, lib.Date(time.Date(2023, 2, 3, 0, 0, 0, 0, time.UTC)))
+		})
+
+		Convey("when existing element", func() {
+			existing := &testStruct{}
+			ts := existing
+			So(Unmarshal(ct, cm, []string{"42", ""}, &ts), ShouldBeNil)
+			So(ts, ShouldEqual, existing)
+			So(**ts.PP, ShouldEqual, 42)
+			So(ts.PPS, ShouldBeNil)
+		})
+	})
 }
@@ -0,0 +1,180 @@
+package lib
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Decimal is an exact fixed-point number (mantissa * 10^-scale)
+// The zero value is 0
+type Decimal struct {
+	mant  *big.Int // never modified once set (nil for 0)
+	scale int
+}
+
+// NewDecimal init a decimal using a mantissa and a scale (ie. NewDecimal(1234, 2) is 12.34)
+// A negative scale multiplies the mantissa (ie. NewDecimal(12, -2) is 1200)
+func NewDecimal(mant int64, scale int) Decimal {
+	if scale < 0 {
+		return Decimal{mant: new(big.Int).Mul(big.NewInt(mant), pow10(-scale))}
+	}
+	return Decimal{mant: big.NewInt(mant), scale: scale}
+}
+
+// ParseDecimal reads an exact decimal number (ie. "-1234.56")
+func ParseDecimal(s string) (Decimal, error) {
+	digits := strings.TrimLeft(s, "+-")
+	if len(s)-len(digits) > 1 {
+		return Decimal{}, fmt.Errorf("invalid decimal %q", s)
+	}
+	intPart, fracPart, _ := strings.Cut(digits, ".")
+	if intPart+fracPart == "" || !isDigits(intPart) || !isDigits(fracPart) {
+		return Decimal{}, fmt.Errorf("invalid decimal %q", s)
+	}
+
+	mant, _ := new(big.Int).SetString(intPart+fracPart, 10)
+	if strings.HasPrefix(s, "-") {
+		mant.Neg(mant)
+	}
+	return Decimal{mant: mant, scale: len(fracPart)}, nil
+}
+
+// isDigits reports whether s is only made of decimal digits
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Scale returns the number of digits after the decimal point
+func (it Decimal) Scale() int {
+	return it.scale
+}
+
+// mantissa returns the mantissa (never nil)
+func (it Decimal) mantissa() *big.Int {
+	if it.mant == nil {
+		return new(big.Int)
+	}
+	return it.mant
+}
+
+// rescale returns the mantissa using a greater scale
+func (it Decimal) rescale(scale int) *big.Int {
+	mant := it.mantissa()
+	if scale == it.scale {
+		return mant
+	}
+	return new(big.Int).Mul(mant, pow10(scale-it.scale))
+}
+
+// pow10 returns 10^n
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// Add returns it + other
+func (it Decimal) Add(other Decimal) Decimal {
+	scale := max(it.scale, other.scale)
+	return Decimal{mant: new(big.Int).Add(it.rescale(scale), other.rescale(scale)), scale: scale}
+}
+
+// Sub returns it - other
+func (it Decimal) Sub(other Decimal) Decimal {
+	scale := max(it.scale, other.scale)
+	return Decimal{mant: new(big.Int).Sub(it.rescale(scale), other.rescale(scale)), scale: scale}
+}
+
+// Mul returns it * other
+func (it Decimal) Mul(other Decimal) Decimal {
+	return Decimal{mant: new(big.Int).Mul(it.mantissa(), other.mantissa()), scale: it.scale + other.scale}
+}
+
+// Cmp compares it and other and returns -1, 0 or +1
+func (it Decimal) Cmp(other Decimal) int {
+	scale := max(it.scale, other.scale)
+	return it.rescale(scale).Cmp(other.rescale(scale))
+}
+
+// Sign returns -1, 0 or +1
+func (it Decimal) Sign() int {
+	return it.mantissa().Sign()
+}
+
+// IsZero reports whether the decimal is 0
+func (it Decimal) IsZero() bool {
+	return it.Sign() == 0
+}
+
+// Round returns the decimal using the given scale and rounding mode
+// A negative scale rounds to tens, hundreds... (ie. 1234.56 is 1200 using the scale -2)
+func (it Decimal) Round(scale int, mode big.RoundingMode) Decimal {
+	if scale >= it.scale {
+		return Decimal{mant: it.rescale(scale), scale: scale}
+	}
+
+	// Truncate, then round away from zero if needed
+	divisor := pow10(it.scale - scale)
+	quo, rem := new(big.Int).QuoRem(it.mantissa(), divisor, new(big.Int))
+	if rem.Sign() != 0 {
+		sign := it.Sign()
+		twice := new(big.Int).Abs(rem)
+		twice.Mul(twice, big.NewInt(2))
+		half := twice.Cmp(divisor) // -1, 0, +1 when below, equal, above half
+		var away bool
+		switch mode {
+		case big.ToNearestEven:
+			away = half > 0 || (half == 0 && quo.Bit(0) == 1)
+		case big.ToNearestAway:
+			away = half >= 0
+		case big.AwayFromZero:
+			away = true
+		case big.ToNegativeInf:
+			away = sign < 0
+		case big.ToPositiveInf:
+			away = sign > 0
+		}
+		if away {
+			quo.Add(quo, big.NewInt(int64(sign)))
+		}
+	}
+	if scale < 0 {
+		return Decimal{mant: quo.Mul(quo, pow10(-scale))} // rounded to tens, hundreds...
+	}
+	return Decimal{mant: quo, scale: scale}
+}
+
+// String returns the decimal using its scale (ie. "-1234.50")
+func (it Decimal) String() string {
+	mant := it.mantissa()
+	digits := new(big.Int).Abs(mant).String()
+	if it.scale > 0 {
+		if len(digits) <= it.scale {
+			digits = strings.Repeat("0", it.scale-len(digits)+1) + digits
+		}
+		digits = digits[:len(digits)-it.scale] + "." + digits[len(digits)-it.scale:]
+	}
+	if mant.Sign() < 0 {
+		return "-" + digits
+	}
+	return digits
+}
+
+// MarshalCSV exports the decimal
+func (it Decimal) MarshalCSV() (string, error) {
+	return it.String(), nil
+}
+
+// UnmarshalCSV imports the decimal
+func (it *Decimal) UnmarshalCSV(s string) error {
+	d, err := ParseDecimal(s)
+	if err != nil {
+		return err
+	}
+	*it = d
+	return nil
+}
@@ -0,0 +1,104 @@
+package lib
+
+import (
+	"math/big"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDecimal(t *testing.T) {
+	Convey("parse", t, func() {
+		for s, exp := range map[string]string{
+			"1234.56":  "1234.56",
+			"-0.05":    "-0.05",
+			"+12":      "12",
+			".5":       "0.5",
+			"3.":       "3",
+			"0012.300": "12.300",
+			"123456789012345678901234567890.123456789": "123456789012345678901234567890.123456789",
+		} {
+			d, err := ParseDecimal(s)
+			So(err, ShouldBeNil)
+			So(d.String(), ShouldEqual, exp)
+		}
+
+		Convey("when ko", func() {
+			for _, s := range []string{"", ".", "-", "1e5", "1.2.3", "--1", "1,5", " 1"} {
+				_, err := ParseDecimal(s)
+				So(err, ShouldBeError, `invalid decimal "`+s+`"`)
+			}
+		})
+	})
+
+	Convey("arithmetic", t, func() {
+		a := NewDecimal(1050, 2) // 10.50
+		b := NewDecimal(-3, 1)   // -0.3
+		So(a.Add(b).String(), ShouldEqual, "10.20")
+		So(a.Sub(b).String(), ShouldEqual, "10.80")
+		So(a.Mul(b).String(), ShouldEqual, "-3.150")
+		So(a.Cmp(b), ShouldEqual, 1)
+		So(b.Cmp(a), ShouldEqual, -1)
+		So(a.Cmp(NewDecimal(105, 1)), ShouldEqual, 0)
+		So(Decimal{}.Add(a).String(), ShouldEqual, "10.50")
+		So(Decimal{}.IsZero(), ShouldBeTrue)
+		So(Decimal{}.String(), ShouldEqual, "0")
+		So(NewDecimal(12, -2).String(), ShouldEqual, "1200")
+		So(NewDecimal(12, -2).Cmp(NewDecimal(1200, 0)), ShouldEqual, 0)
+	})
+
+	Convey("round", t, func() {
+		type test struct {
+			mode big.RoundingMode
+			exp  []string
+		}
+		inputs := []string{"1.25", "1.35", "-1.25", "1.251", "1.2"}
+		for _, tst := range []test{
+			{mode: big.ToNearestEven, exp: []string{"1.2", "1.4", "-1.2", "1.3", "1.2"}},
+			{mode: big.ToNearestAway, exp: []string{"1.3", "1.4", "-1.3", "1.3", "1.2"}},
+			{mode: big.ToZero, exp: []string{"1.2", "1.3", "-1.2", "1.2", "1.2"}},
+			{mode: big.AwayFromZero, exp: []string{"1.3", "1.4", "-1.3", "1.3", "1.2"}},
+			{mode: big.ToNegativeInf, exp: []string{"1.2", "1.3", "-1.3", "1.2", "1.2"}},
+			{mode: big.ToPositiveInf, exp: []string{"1.3", "1.4", "-1.2", "1.3", "1.2"}},
+		} {
+			for i, input := range inputs {
+				d, err := ParseDecimal(input)
+				So(err, ShouldBeNil)
+				So(d.Round(1, tst.mode).String(), ShouldEqual, tst.exp[i])
+			}
+		}
+
+		Convey("when greater scale", func() {
+			So(NewDecimal(5, 0).Round(2, big.ToZero).String(), ShouldEqual, "5.00")
+		})
+
+		Convey("when negative scale", func() {
+			So(NewDecimal(123456, 2).Round(-2, big.ToNearestEven).String(), ShouldEqual, "1200")
+			So(NewDecimal(-125, 0).Round(-1, big.ToNearestAway).String(), ShouldEqual, "-130")
+			So(NewDecimal(49, 0).Round(-2, big.ToNearestEven).Scale(), ShouldEqual, 0)
+		})
+	})
+
+	Convey("marshal", t, func() {
+		res, err := NewDecimal(-5, 3).MarshalCSV()
+		So(err, ShouldBeNil)
+		So(res, ShouldEqual, "-0.005")
+	})
+
+	Convey("unmarshal", t, func() {
+		Convey("when ok", func() {
+			var res Decimal
+			err := res.UnmarshalCSV("1234.56")
+			So(err, ShouldBeNil)
+			So(res.Cmp(NewDecimal(123456, 2)), ShouldEqual, 0)
+			So(res.Scale(), ShouldEqual, 2)
+		})
+
+		Convey("when ko", func() {
+			var res Decimal
+			err := res.UnmarshalCSV("oups")
+			So(err, ShouldBeError, `invalid decimal "oups"`)
+			So(res.IsZero(), ShouldBeTrue)
+		})
+	})
+}
@@ -1,5 +1,7 @@
 package lib
 
+import "context"
+
 // Marshaler defines the unique method for marshaling a CSV field
 type Marshaler interface {
 	MarshalCSV() (string, error)
@@ -9,3 +11,62 @@ type Marshaler interface {
 type Unmarshaler interface {
 	UnmarshalCSV(string) error
 }
+
+// ContextUnmarshaler defines the unique method for unmarshaling a CSV field using the decoding context
+// It takes precedence over Unmarshaler
+type ContextUnmarshaler interface {
+	UnmarshalCSVContext(context.Context, string) error
+}
+
+// FieldContext describes the field being decoded or encoded (see FieldUnmarshaler and FieldMarshaler)
+type FieldContext struct {
+	Context context.Context
+	Row     int               // record index
+	Col     int               // column index
+	Header  string            // column header, only defined by the WithHeader option (empty otherwise)
+	Field   string            // struct field name
+	Options map[string]string // tag options, by name (ie. `format=...`), a copy for each call
+	Raw     []string          // raw record (nil when encoding)
+}
+
+// FieldUnmarshaler defines the unique method for unmarshaling a CSV field using its context
+// It takes precedence over Unmarshaler and ContextUnmarshaler
+type FieldUnmarshaler interface {
+	UnmarshalCSVField(FieldContext, string) error
+}
+
+// FieldMarshaler defines the unique method for marshaling a CSV field using its context
+// It takes precedence over Marshaler
+type FieldMarshaler interface {
+	MarshalCSVField(FieldContext) (string, error)
+}
+
+// MultiMarshaler defines the unique method for marshaling a field into several CSV columns (ie. `csv:"3+4"`)
+type MultiMarshaler interface {
+	MarshalCSVColumns() ([]string, error)
+}
+
+// MultiUnmarshaler defines the unique method for unmarshaling a field from several CSV columns (ie. `csv:"3+4"`)
+type MultiUnmarshaler interface {
+	UnmarshalCSVColumns([]string) error
+}
+
+// Zeroer defines the unique method for checking if a CSV field is empty (see omitempty)
+type Zeroer interface {
+	IsZero() bool
+}
+
+// BeforeUnmarshaler defines the hook called with the raw record, before unmarshaling the fields of a row
+type BeforeUnmarshaler interface {
+	BeforeUnmarshalCSV([]string) error
+}
+
+// AfterUnmarshaler defines the hook called after unmarshaling the fields of a row (ie. cross-field validation)
+type AfterUnmarshaler interface {
+	AfterUnmarshalCSV() error
+}
+
+// BeforeMarshaler defines the hook called before marshaling the fields of a row (ie. computing derived fields)
+type BeforeMarshaler interface {
+	BeforeMarshalCSV() error
+}
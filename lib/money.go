@@ -0,0 +1,83 @@
+package lib
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Money is a decimal amount with an ISO 4217 currency code (ie. "12.50 EUR")
+type Money struct {
+	Amount   Decimal
+	Currency string
+}
+
+// ParseMoney reads an amount followed by a currency code (ie. "12.50 EUR")
+// An empty string is the zero value
+func ParseMoney(s string) (Money, error) {
+	if strings.TrimSpace(s) == "" {
+		return Money{}, nil
+	}
+	amount, currency, ok := strings.Cut(strings.TrimSpace(s), " ")
+	if !ok || !isCurrency(currency) {
+		return Money{}, fmt.Errorf("invalid money %q", s)
+	}
+	d, err := ParseDecimal(amount)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{Amount: d, Currency: currency}, nil
+}
+
+// isCurrency reports whether s is made of 3 upper case letters
+func isCurrency(s string) bool {
+	if len(s) != 3 {
+		return false
+	}
+	for _, c := range s {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// IsZero reports whether the money has no amount and no currency
+func (it Money) IsZero() bool {
+	return it.Amount.IsZero() && it.Currency == ""
+}
+
+// Round returns the money using the given scale and rounding mode
+func (it Money) Round(scale int, mode big.RoundingMode) Money {
+	return Money{Amount: it.Amount.Round(scale, mode), Currency: it.Currency}
+}
+
+// String returns the amount followed by the currency (ie. "12.50 EUR")
+// The zero value is an empty string, and a missing currency is omitted
+func (it Money) String() string {
+	switch {
+	case it.IsZero():
+		return ""
+	case it.Currency == "":
+		return it.Amount.String()
+	}
+	return it.Amount.String() + " " + it.Currency
+}
+
+// MarshalCSV exports the money (the currency is required, unless zero)
+func (it Money) MarshalCSV() (string, error) {
+	if it.Currency == "" && !it.IsZero() {
+		return "", fmt.Errorf("no currency defined for %s", it.Amount)
+	}
+	return it.String(), nil
+}
+
+// UnmarshalCSV imports the money
+func (it *Money) UnmarshalCSV(s string) error {
+	m, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*it = m
+	return nil
+}
@@ -0,0 +1,57 @@
+package lib
+
+import (
+	"math/big"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMoney(t *testing.T) {
+	Convey("marshal", t, func() {
+		res, err := Money{Amount: NewDecimal(1250, 2), Currency: "EUR"}.MarshalCSV()
+		So(err, ShouldBeNil)
+		So(res, ShouldEqual, "12.50 EUR")
+	})
+
+	Convey("round trip of the zero value", t, func() {
+		res, err := Money{}.MarshalCSV()
+		So(err, ShouldBeNil)
+		So(res, ShouldEqual, "")
+
+		var m Money
+		So(m.UnmarshalCSV(res), ShouldBeNil)
+		So(m.IsZero(), ShouldBeTrue)
+
+		_, err = Money{Amount: NewDecimal(5, 0)}.MarshalCSV()
+		So(err, ShouldBeError, "no currency defined for 5")
+	})
+
+	Convey("round", t, func() {
+		m := Money{Amount: NewDecimal(12345, 3), Currency: "USD"}
+		So(m.Round(2, big.ToNearestAway).String(), ShouldEqual, "12.35 USD")
+	})
+
+	Convey("unmarshal", t, func() {
+		Convey("when ok", func() {
+			var res Money
+			err := res.UnmarshalCSV("12.50 EUR")
+			So(err, ShouldBeNil)
+			So(res.Currency, ShouldEqual, "EUR")
+			So(res.Amount.String(), ShouldEqual, "12.50")
+			So(res.IsZero(), ShouldBeFalse)
+		})
+
+		Convey("when ko", func() {
+			var res Money
+			for _, s := range []string{"12.50", "12.50 eur", "12.50 EURO", "EUR 12.50"} {
+				err := res.UnmarshalCSV(s)
+				So(err, ShouldBeError, `invalid money "`+s+`"`)
+				So(res.IsZero(), ShouldBeTrue)
+			}
+
+			err := res.UnmarshalCSV("1,50 EUR")
+			So(err, ShouldBeError, `invalid decimal "1,50"`)
+		})
+	})
+}
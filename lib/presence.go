@@ -0,0 +1,23 @@
+package lib
+
+// Presence records the fields decoded from a non empty value
+// Add it as a field of the row struct (without any csv tag) to be filled when decoding
+type Presence struct {
+	fields map[string]struct{}
+}
+
+// NewPresence init an empty presence
+func NewPresence() Presence {
+	return Presence{fields: make(map[string]struct{})}
+}
+
+// Set marks the field as decoded from a non empty value
+func (it Presence) Set(field string) {
+	it.fields[field] = struct{}{}
+}
+
+// IsSet reports whether the field was decoded from a non empty value
+func (it Presence) IsSet(field string) bool {
+	_, ok := it.fields[field]
+	return ok
+}
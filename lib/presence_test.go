@@ -0,0 +1,21 @@
+package lib
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPresence(t *testing.T) {
+	Convey("is set", t, func() {
+		presence := NewPresence()
+		presence.Set("Name")
+		So(presence.IsSet("Name"), ShouldBeTrue)
+		So(presence.IsSet("Other"), ShouldBeFalse)
+	})
+
+	Convey("when zero value", t, func() {
+		var presence Presence
+		So(presence.IsSet("Name"), ShouldBeFalse)
+	})
+}
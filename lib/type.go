@@ -1,6 +1,8 @@
 package lib
 
 import (
+	"fmt"
+	"strings"
 	"time"
 )
 
@@ -34,8 +36,16 @@ func (it Bool) MarshalCSV() (string, error) {
 }
 
 // UnmarshalCSV imports the boolean
+// Accepts "true", "t", "1" and "false", "f", "0" (case insensitive), whatever the bool options or WithBoolValues
 func (it *Bool) UnmarshalCSV(s string) error {
-	*it = (s == "true")
+	switch strings.ToLower(s) {
+	case "true", "t", "1":
+		*it = true
+	case "false", "f", "0":
+		*it = false
+	default:
+		return fmt.Errorf("invalid boolean %q", s)
+	}
 	return nil
 }
 
@@ -47,6 +57,11 @@ func (it Date) MarshalCSV() (string, error) {
 	return time.Time(it).Format(time.DateOnly), nil
 }
 
+// IsZero reports whether the date is empty
+func (it Date) IsZero() bool {
+	return time.Time(it).IsZero()
+}
+
 // UnmarshalCSV imports the date
 func (it *Date) UnmarshalCSV(s string) error {
 	tm, err := time.Parse(time.DateOnly, s)
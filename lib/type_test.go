@@ -36,6 +36,20 @@ func TestBool(t *testing.T) {
 			So(err, ShouldBeNil)
 			So(res, ShouldEqual, Bool(false))
 		})
+
+		Convey("when case insensitive", func() {
+			var res Bool
+			err := res.UnmarshalCSV("TRUE")
+			So(err, ShouldBeNil)
+			So(res, ShouldEqual, Bool(true))
+		})
+
+		Convey("when ko", func() {
+			var res Bool
+			err := res.UnmarshalCSV("yes")
+			So(err, ShouldBeError, `invalid boolean "yes"`)
+			So(res, ShouldBeZeroValue)
+		})
 	})
 }
 
@@ -70,6 +84,11 @@ func TestDate(t *testing.T) {
 		So(res, ShouldEqual, "2023-12-02")
 	})
 
+	Convey("is zero", t, func() {
+		So(Date{}.IsZero(), ShouldBeTrue)
+		So(Date(time.Date(2023, 12, 2, 0, 0, 0, 0, time.UTC)).IsZero(), ShouldBeFalse)
+	})
+
 	Convey("unmarshal", t, func() {
 		Convey("when ok", func() {
 			var res Date
@@ -0,0 +1,81 @@
+package gocsv
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sbiemont/gocsv/internal"
+)
+
+// DecodeInto decodes a csv struct into the existing items (one item per row, allocated if nil)
+// Only the non empty values are decoded, the other fields are left untouched
+// A decoded value is always a new one (the existing slices, maps or pointers are not modified)
+// It returns the names of the changed fields, per row
+func DecodeInto[T any](data [][]string, items []*T, opts ...Option) ([][]string, error) {
+	dec, err := NewDecoder[T](opts...)
+	if err != nil {
+		return nil, err
+	}
+	return dec.DecodeInto(data, items)
+}
+
+// DecodeInto decodes a csv struct into the existing items (see DecodeInto)
+func (dec *Decoder[T]) DecodeInto(data [][]string, items []*T) ([][]string, error) {
+	return dec.DecodeIntoContext(context.Background(), data, items)
+}
+
+// DecodeIntoContext decodes a csv struct into the existing items, until the context is done
+// On error, the previous rows are already decoded
+func (dec *Decoder[T]) DecodeIntoContext(ctx context.Context, data [][]string, items []*T) ([][]string, error) {
+	if len(data) != len(items) {
+		return nil, fmt.Errorf("%d rows expected, got %d items", len(data), len(items))
+	}
+
+	res := make([][]string, len(data))
+	for i, row := range data {
+		err := checkContext(ctx, i)
+		if err != nil {
+			return nil, err
+		}
+		if items[i] == nil {
+			items[i] = new(T)
+		}
+		changed, err := internal.MergeContext(ctx, dec.merge, dec.cm, internal.Row{Index: i}, row, items[i])
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", i, err)
+		}
+		res[i] = changed
+	}
+	return res, nil
+}
+
+// MergeByKey decodes a csv struct into the existing items, found using the value of the key field
+// Only the non empty values are decoded, the other fields are left untouched
+// Every key is checked before merging (an unknown key returns an error)
+// It returns the names of the changed fields, per row
+func MergeByKey[K comparable, T any](existing map[K]*T, data [][]string, keyField string, opts ...Option) ([][]string, error) {
+	dec, err := NewDecoder[T](opts...)
+	if err != nil {
+		return nil, err
+	}
+	field, err := internal.KeyField[K](dec.ct, keyField)
+	if err != nil {
+		return nil, err
+	}
+
+	// Find every item
+	ctx := context.Background()
+	items := make([]*T, len(data))
+	for i, row := range data {
+		key, err := internal.UnmarshalKey[K](ctx, dec.ct, dec.cm, internal.Row{Index: i}, field, row)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", i, err)
+		}
+		item, ok := existing[key]
+		if !ok || item == nil {
+			return nil, fmt.Errorf("row %d: unknown key %v", i, key)
+		}
+		items[i] = item
+	}
+	return dec.DecodeIntoContext(ctx, data, items)
+}
@@ -0,0 +1,83 @@
+package gocsv
+
+import (
+	"github.com/sbiemont/gocsv/internal"
+)
+
+// Option defines a global setting applied to every field
+type Option = internal.Option
+
+// WithBoolValues sets the accepted true and false values (case insensitive)
+// The first value of each list is used when encoding
+func WithBoolValues(trueValues, falseValues []string) Option {
+	return internal.WithBoolValues(trueValues, falseValues)
+}
+
+// WithNullValues sets the values considered as empty (in addition to the empty string)
+// The first value is used when encoding a nil value
+func WithNullValues(nullValues ...string) Option {
+	return internal.WithNullValues(nullValues...)
+}
+
+// WithTimeLayout sets the layout used to decode and encode a time.Time (default is time.RFC3339Nano)
+func WithTimeLayout(layout string) Option {
+	return internal.WithTimeLayout(layout)
+}
+
+// WithTrimSpace trims the leading and trailing white spaces of every value before decoding
+func WithTrimSpace() Option {
+	return internal.WithTrimSpace()
+}
+
+// WithHeader sets the column headers (given to the lib.FieldUnmarshaler and lib.FieldMarshaler fields)
+func WithHeader(header ...string) Option {
+	return internal.WithHeader(header...)
+}
+
+// WithMethod encodes the result of a method `func() V` (or `func() (V, error)`) as a column (ignored on decode)
+// The csv tag defines the column (or its header, see WithHeader) and the options, as for a field
+func WithMethod(csvTag, method string) Option {
+	return internal.WithMethod(csvTag, method)
+}
+
+// EmptyPolicy defines how an empty value is decoded
+type EmptyPolicy = internal.EmptyPolicy
+
+// Available empty policies
+const (
+	EmptyError   = internal.EmptyError   // an error is returned
+	EmptyZero    = internal.EmptyZero    // the field is set to its zero value
+	EmptyDefault = internal.EmptyDefault // the field is decoded using its default value
+	EmptyMissing = internal.EmptyMissing // the field is left untouched
+)
+
+// WithEmptyPolicy sets the behavior when decoding an empty value
+func WithEmptyPolicy(policy EmptyPolicy) Option {
+	return internal.WithEmptyPolicy(policy)
+}
+
+// RegisterConverter defines the marshal and unmarshal functions of a type for every decoder and encoder
+// It allows to use a type without implementing lib.Marshaler and lib.Unmarshaler
+// Converters shall be registered before creating the decoders and encoders (ie. in an init function)
+func RegisterConverter[V any](marshal func(V) (string, error), unmarshal func(string) (V, error)) {
+	internal.RegisterConverter(marshal, unmarshal)
+}
+
+// WithConverter defines the marshal and unmarshal functions of a type for a single decoder or encoder
+// It takes precedence over the converters defined by RegisterConverter
+func WithConverter[V any](marshal func(V) (string, error), unmarshal func(string) (V, error)) Option {
+	return internal.WithConverter(marshal, unmarshal)
+}
+
+// RegisterFactory defines the function allocating the dynamic value of an interface field, for every decoder
+// The function chooses the type depending on the input, then the returned value (or pointer) is unmarshaled
+// Factories shall be registered before creating the decoders (ie. in an init function)
+func RegisterFactory[I any](fct func(string) (I, error)) {
+	internal.RegisterFactory(fct)
+}
+
+// WithFactory defines the function allocating the dynamic value of an interface field, for a single decoder
+// It takes precedence over the factories defined by RegisterFactory
+func WithFactory[I any](fct func(string) (I, error)) Option {
+	return internal.WithFactory(fct)
+}
@@ -0,0 +1,152 @@
+package gocsv
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+
+	"github.com/sbiemont/gocsv/internal"
+)
+
+// Kinds associates each discriminator value with a kind of row (ie. {"H": Header{}, "D": Detail{}})
+// Each kind is a struct (or a pointer to struct) implementing the interface of the decoded rows
+type Kinds = internal.Kinds
+
+// PolymorphicDecoder decodes csv records into several kinds of rows, using a discriminator column
+// A decoder is not safe for concurrent use
+type PolymorphicDecoder[I any] struct {
+	ck internal.CacheKinds[I]
+}
+
+// NewPolymorphicDecoder init a decoder using the given kinds, discriminator column and options
+func NewPolymorphicDecoder[I any](kinds Kinds, col int, opts ...Option) (*PolymorphicDecoder[I], error) {
+	ck, err := internal.NewCacheKinds[I](kinds, col, opts...)
+	if err != nil {
+		return nil, err
+	}
+	err = internal.PrepareKindsUnmarshaler(ck)
+	if err != nil {
+		return nil, err
+	}
+	return &PolymorphicDecoder[I]{ck: ck}, nil
+}
+
+// DecodePolymorphic decodes a csv struct into several kinds of rows, using a discriminator column
+func DecodePolymorphic[I any](data [][]string, kinds Kinds, col int, opts ...Option) ([]I, error) {
+	dec, err := NewPolymorphicDecoder[I](kinds, col, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return dec.Decode(data)
+}
+
+// Decode a csv struct into the decoder kinds of rows
+func (dec *PolymorphicDecoder[I]) Decode(data [][]string) ([]I, error) {
+	return dec.DecodeContext(context.Background(), data)
+}
+
+// DecodeContext decodes a csv struct into the decoder kinds of rows, until the context is done
+func (dec *PolymorphicDecoder[I]) DecodeContext(ctx context.Context, data [][]string) ([]I, error) {
+	res := make([]I, len(data))
+
+	// Read all
+	for i, row := range data {
+		err := checkContext(ctx, i)
+		if err != nil {
+			return nil, err
+		}
+		err = internal.UnmarshalKind(ctx, dec.ck, internal.Row{Index: i}, row, &res[i])
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", i, err)
+		}
+	}
+	return res, nil
+}
+
+// DecodeReader reads and decodes every csv record of the reader into the decoder kinds of rows
+// The source names the reader (ie. a file name), and fills the `csv:"@source"` fields
+func (dec *PolymorphicDecoder[I]) DecodeReader(r *csv.Reader, source string) ([]I, error) {
+	return dec.DecodeReaderContext(context.Background(), r, source)
+}
+
+// DecodeReaderContext reads and decodes every csv record of the reader, until the context is done
+func (dec *PolymorphicDecoder[I]) DecodeReaderContext(ctx context.Context, r *csv.Reader, source string) ([]I, error) {
+	var res []I
+	err := readRecords(ctx, r, source, func(row internal.Row, record []string) error {
+		var item I
+		err := internal.UnmarshalKind(ctx, dec.ck, row, record, &item)
+		if err != nil {
+			return err
+		}
+		res = append(res, item)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// PolymorphicEncoder encodes several kinds of rows into csv records, writing the discriminator column
+// An encoder is not safe for concurrent use
+type PolymorphicEncoder[I any] struct {
+	ck internal.CacheKinds[I]
+}
+
+// NewPolymorphicEncoder init an encoder using the given kinds, discriminator column and options
+func NewPolymorphicEncoder[I any](kinds Kinds, col int, opts ...Option) (*PolymorphicEncoder[I], error) {
+	ck, err := internal.NewCacheKinds[I](kinds, col, opts...)
+	if err != nil {
+		return nil, err
+	}
+	err = internal.PrepareKindsMarshaler(ck)
+	if err != nil {
+		return nil, err
+	}
+	return &PolymorphicEncoder[I]{ck: ck}, nil
+}
+
+// EncodePolymorphic encodes several kinds of rows into a csv struct, writing the discriminator column
+func EncodePolymorphic[I any](data []I, kinds Kinds, col int, opts ...Option) ([][]string, error) {
+	enc, err := NewPolymorphicEncoder[I](kinds, col, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return enc.Encode(data)
+}
+
+// Encode the rows into a csv struct
+func (enc *PolymorphicEncoder[I]) Encode(data []I) ([][]string, error) {
+	return enc.EncodeContext(context.Background(), data)
+}
+
+// EncodeContext encodes the rows into a csv struct, until the context is done
+func (enc *PolymorphicEncoder[I]) EncodeContext(ctx context.Context, data []I) ([][]string, error) {
+	res := make([][]string, len(data))
+
+	// Read all
+	for i, item := range data {
+		err := checkContext(ctx, i)
+		if err != nil {
+			return nil, err
+		}
+		row, err := internal.MarshalKind(ctx, enc.ck, i, item)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", i, err)
+		}
+		res[i] = row
+	}
+	return res, nil
+}
+
+// EncodeWriter encodes the rows and writes them into the csv writer (flushed at the end)
+func (enc *PolymorphicEncoder[I]) EncodeWriter(w *csv.Writer, data []I) error {
+	return enc.EncodeWriterContext(context.Background(), w, data)
+}
+
+// EncodeWriterContext encodes the rows and writes them into the csv writer, until the context is done
+func (enc *PolymorphicEncoder[I]) EncodeWriterContext(ctx context.Context, w *csv.Writer, data []I) error {
+	return writeRecords(ctx, w, len(data), func(i int) ([]string, error) {
+		return internal.MarshalKind(ctx, enc.ck, i, data[i])
+	})
+}